@@ -0,0 +1,249 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// A Result is the outcome of updating one hostname of a batch.
+type Result struct {
+	Hostname string
+	UpdateResult
+	Err error
+}
+
+// UpdateMany changes several hostnames to ip in a single request, as the
+// protocol allows, and returns one Result per hostname in the same order.
+// Hostnames disabled after an abuse response are not sent and fail with
+// ErrHostDisabled. The returned error is non-nil only if the request
+// itself failed or the response could not be matched to the hostnames,
+// in which case it is a ResponseMismatchError.
+func (c *Client) UpdateMany(ctx context.Context, hostnames []string, ip net.IP, opts ...UpdateOption) ([]Result, error) {
+	params, err := newUpdateParams(opts)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, len(hostnames))
+	var send []int // indexes of hostnames to send
+	for i, hostname := range hostnames {
+		r := &results[i]
+		r.Hostname = hostname
+		name, err := c.normalizeHostname(hostname)
+		switch {
+		case err != nil:
+			r.Err = err
+		case c.isDisabled(name):
+			r.Hostname, r.Err = name, ErrHostDisabled
+		default:
+			r.Hostname = name
+			send = append(send, i)
+		}
+	}
+	if len(send) == 0 {
+		return results, nil
+	}
+	names := make([]string, len(send))
+	for j, i := range send {
+		names[j] = results[i].Hostname
+	}
+	if err := c.pace(ctx); err != nil {
+		return nil, err
+	}
+	tctx, tr := c.withTrace(ctx)
+	resp, body, err := c.do(tctx, strings.Join(names, ","), ip, params)
+	if err != nil {
+		return nil, err
+	}
+	rl := parseRateLimit(resp.Header, time.Now())
+	timings := tr.done()
+	var header http.Header
+	if c.keepHeaders {
+		header = resp.Header
+	}
+	skew := c.clockSkew(resp.Header, time.Now())
+	lines := responseLines(body)
+	if len(lines) != len(send) {
+		return nil, &ResponseMismatchError{Hosts: len(send), Lines: len(lines), Body: string(body)}
+	}
+	for j, i := range send {
+		r := &results[i]
+		res := c.parse([]byte(lines[j]))
+		res.RateLimit, res.Timings, res.Header = rl, timings, header
+		if skew != nil {
+			res.Warnings = append(res.Warnings, skew)
+		}
+		r.UpdateResult, r.Err = c.finish(ctx, r.Hostname, ip, params, res)
+	}
+	return results, nil
+}
+
+// UpdateBatch updates each of hostnames to ip with its own request,
+// keeping at most concurrency requests in flight; concurrency below 1
+// means 1. Requests still respect the Client's MinInterval, so rate and
+// parallelism can be tuned independently. Results are in the order of
+// hostnames.
+//
+// If ctx is done before every hostname was sent, the remaining results
+// fail with ctx.Err(), which UpdateBatch also returns once the requests
+// in flight have finished.
+func (c *Client) UpdateBatch(ctx context.Context, hostnames []string, ip net.IP, concurrency int, opts ...UpdateOption) ([]Result, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]Result, len(hostnames))
+	for i, name := range hostnames {
+		results[i].Hostname = name
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < len(hostnames); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				r := &results[i]
+				r.UpdateResult, r.Err = c.Update(ctx, r.Hostname, ip, opts...)
+			}
+		}()
+	}
+	sent := 0
+loop:
+	for ; sent < len(hostnames); sent++ {
+		select {
+		case next <- sent:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(next)
+	wg.Wait()
+	if sent == len(hostnames) {
+		return results, nil
+	}
+	for i := sent; i < len(hostnames); i++ {
+		results[i].Err = ctx.Err()
+	}
+	return results, ctx.Err()
+}
+
+// responseLines splits body into its non-blank lines.
+func responseLines(body []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// ErrResponseMismatch matches a ResponseMismatchError with errors.Is.
+var ErrResponseMismatch = errors.New("dyndns: response lines do not match hostnames")
+
+// A ResponseMismatchError is returned by UpdateMany when the server sends
+// a different number of result lines than hostnames were requested, so
+// results cannot be safely matched to hostnames.
+type ResponseMismatchError struct {
+	Hosts, Lines int
+	Body         string // raw response body
+}
+
+// Error satisfies the built-in error interface.
+func (e *ResponseMismatchError) Error() string {
+	return fmt.Sprintf("%v: %d hostnames, %d lines", ErrResponseMismatch, e.Hosts, e.Lines)
+}
+
+// Is reports whether target is ErrResponseMismatch.
+func (e *ResponseMismatchError) Is(target error) bool {
+	return target == ErrResponseMismatch
+}
+
+// AggregateErrors returns nil if every result succeeded, or an error
+// joining one error per failed hostname. The joined errors wrap the
+// per-host errors, so errors.Is(err, ErrNoHost) reports whether any
+// hostname failed with nohost.
+func AggregateErrors(results []Result) error {
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, &HostError{r.Hostname, r.Err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// A HostError is an error for a single hostname of a batch.
+type HostError struct {
+	Hostname string
+	Err      error
+}
+
+// Error satisfies the built-in error interface.
+func (e *HostError) Error() string {
+	return e.Hostname + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *HostError) Unwrap() error {
+	return e.Err
+}
+
+// DiffResults summarizes a batch update for logging, one line per result
+// in order: whether the hostname changed address since before, stayed the
+// same, or failed. For example:
+//
+//	changed   a.example.org 1.2.3.4 -> 5.6.7.8
+//	unchanged b.example.org 5.6.7.8
+//	failed    c.example.org: dyndns: nohost: hostname does not exist in this account
+func DiffResults(before map[string]net.IP, after []Result) string {
+	var b strings.Builder
+	for _, r := range after {
+		old, now := before[r.Hostname], r.IP
+		switch {
+		case r.Err != nil:
+			fmt.Fprintf(&b, "failed    %s: %v\n", r.Hostname, r.Err)
+		case r.Code == CodeNoChange || now != nil && now.Equal(old):
+			if now == nil {
+				now = old
+			}
+			fmt.Fprintf(&b, "unchanged %s %s\n", r.Hostname, ipOrNone(now))
+		default:
+			fmt.Fprintf(&b, "changed   %s %s -> %s\n", r.Hostname, ipOrNone(old), ipOrNone(now))
+		}
+	}
+	return b.String()
+}
+
+func ipOrNone(ip net.IP) string {
+	if ip == nil {
+		return "none"
+	}
+	return ip.String()
+}
+
+// ParseHostnames splits a user-entered list of hostnames separated by
+// commas and whitespace, for UpdateMany or a Monitor's Hosts. Hostnames
+// are lowercased, and duplicates and empty entries are dropped, keeping
+// the order of first appearance.
+func ParseHostnames(input string) []string {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	seen := make(map[string]bool, len(fields))
+	hostnames := []string{}
+	for _, f := range fields {
+		h := strings.ToLower(f)
+		if !seen[h] {
+			seen[h] = true
+			hostnames = append(hostnames, h)
+		}
+	}
+	return hostnames
+}
@@ -0,0 +1,192 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUpdateMany(t *testing.T) {
+	c := newTestClient(t, "good 1.2.3.4\nnohost\nnochg 1.2.3.4\n")
+	hosts := []string{"a.dyndns.org", "b.dyndns.org", "c.dyndns.org"}
+	results, err := c.UpdateMany(context.Background(), hosts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		code Code
+		err  error
+	}{{CodeGood, nil}, {CodeNoHost, ErrNoHost}, {CodeNoChange, nil}}
+	for i, r := range results {
+		if r.Hostname != hosts[i] || r.Code != want[i].code || r.Err != want[i].err {
+			t.Errorf("results[%d] = %+v, want %s %v", i, r, want[i].code, want[i].err)
+		}
+	}
+}
+
+func TestUpdateManyQuery(t *testing.T) {
+	var q url.Values
+	c := &Client{URL: newTestServer(t, "good 1.2.3.4", nil, &q).URL}
+	c.UpdateMany(context.Background(), []string{"a.dyndns.org", "b.dyndns.org"}, net.IPv4(1, 2, 3, 4))
+	if got := q.Get("hostname"); got != "a.dyndns.org,b.dyndns.org" {
+		t.Errorf("hostname = %q", got)
+	}
+}
+
+func TestAggregateErrors(t *testing.T) {
+	if err := AggregateErrors([]Result{{Hostname: "a"}, {Hostname: "b"}}); err != nil {
+		t.Errorf("all succeeded: err = %v", err)
+	}
+	err := AggregateErrors([]Result{
+		{Hostname: "a", Err: ErrNoHost},
+		{Hostname: "b"},
+		{Hostname: "c", Err: &ResponseError{ErrAbuse, "blocked"}},
+	})
+	if !errors.Is(err, ErrNoHost) || !errors.Is(err, ErrAbuse) || errors.Is(err, ErrAuth) {
+		t.Errorf("err = %v does not wrap the per-host errors", err)
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "a: dyndns: nohost") || !strings.Contains(msg, "c: dyndns: abuse") || strings.Contains(msg, "b:") {
+		t.Errorf("err = %q", msg)
+	}
+}
+
+func TestUpdateManyLineCount(t *testing.T) {
+	hosts := []string{"a.dyndns.org", "b.dyndns.org", "c.dyndns.org"}
+	for _, tt := range []struct {
+		body     string
+		mismatch bool
+	}{
+		{"good 1.2.3.4\nnohost\n", true},
+		{"good 1.2.3.4\r\nnohost\r\ngood 1.2.3.4\r\n", false},
+		{"good 1.2.3.4\n\nnohost\ngood 1.2.3.4\n\n", false},
+		{"good 1.2.3.4\nnohost\ngood 1.2.3.4\nabuse\n", true},
+		{"", true},
+	} {
+		results, err := newTestClient(t, tt.body).UpdateMany(context.Background(), hosts, nil)
+		var mismatch *ResponseMismatchError
+		if got := errors.As(err, &mismatch); got != tt.mismatch {
+			t.Errorf("%q: err = %v, want mismatch %t", tt.body, err, tt.mismatch)
+			continue
+		}
+		if tt.mismatch {
+			if mismatch.Body != tt.body || mismatch.Hosts != len(hosts) || results != nil {
+				t.Errorf("%q: mismatch = %+v, results = %v", tt.body, mismatch, results)
+			}
+			if !errors.Is(err, ErrResponseMismatch) {
+				t.Errorf("%q: err does not match ErrResponseMismatch", tt.body)
+			}
+		} else if results[1].Err != ErrNoHost || results[2].Code != CodeGood {
+			t.Errorf("%q: results = %+v", tt.body, results)
+		}
+	}
+}
+
+func TestDiffResults(t *testing.T) {
+	before := map[string]net.IP{
+		"a.example.org": net.IPv4(1, 2, 3, 4),
+		"b.example.org": net.IPv4(5, 6, 7, 8),
+		"d.example.org": net.IPv4(5, 6, 7, 8),
+	}
+	after := []Result{
+		{Hostname: "a.example.org", UpdateResult: UpdateResult{Code: CodeGood, IP: net.IPv4(5, 6, 7, 8)}},
+		{Hostname: "b.example.org", UpdateResult: UpdateResult{Code: CodeNoChange, IP: net.IPv4(5, 6, 7, 8)}},
+		{Hostname: "c.example.org", UpdateResult: UpdateResult{Code: CodeNoHost}, Err: ErrNoHost},
+		{Hostname: "d.example.org", UpdateResult: UpdateResult{Code: CodeNoChange}},
+		{Hostname: "e.example.org", UpdateResult: UpdateResult{Code: CodeGood, IP: net.IPv4(5, 6, 7, 8)}},
+	}
+	want := "changed   a.example.org 1.2.3.4 -> 5.6.7.8\n" +
+		"unchanged b.example.org 5.6.7.8\n" +
+		"failed    c.example.org: dyndns: nohost: hostname does not exist in this account\n" +
+		"unchanged d.example.org 5.6.7.8\n" +
+		"changed   e.example.org none -> 5.6.7.8\n"
+	if got := DiffResults(before, after); got != want {
+		t.Errorf("DiffResults =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseHostnames(t *testing.T) {
+	for _, tt := range []struct {
+		input string
+		want  []string
+	}{
+		{"", []string{}},
+		{" , ,\t", []string{}},
+		{"a.example.org", []string{"a.example.org"}},
+		{"A.Example.org, b.example.org", []string{"a.example.org", "b.example.org"}},
+		{" b.example.org,,a.example.org\n\tB.EXAMPLE.ORG a.example.org ", []string{"b.example.org", "a.example.org"}},
+		{"a.example.org,b.example.org c.example.org", []string{"a.example.org", "b.example.org", "c.example.org"}},
+	} {
+		if got := ParseHostnames(tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseHostnames(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateBatchConcurrency(t *testing.T) {
+	var inFlight, peak int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for p := atomic.LoadInt32(&peak); n > p && !atomic.CompareAndSwapInt32(&peak, p, n); p = atomic.LoadInt32(&peak) {
+		}
+		time.Sleep(20 * time.Millisecond)
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+	c := &Client{URL: srv.URL}
+
+	var hosts []string
+	for i := 0; i < 12; i++ {
+		hosts = append(hosts, fmt.Sprintf("h%d.dyndns.org", i))
+	}
+	results, err := c.UpdateBatch(context.Background(), hosts, nil, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := atomic.LoadInt32(&peak); p > 3 || p < 2 {
+		t.Errorf("peak of %d concurrent requests, want 3", p)
+	}
+	for i, r := range results {
+		if r.Hostname != hosts[i] || r.Err != nil || r.Code != CodeGood {
+			t.Errorf("result %d = %+v", i, r)
+		}
+	}
+}
+
+func TestUpdateBatchCancel(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+	defer close(release)
+	c := &Client{URL: srv.URL}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	hosts := []string{"a.dyndns.org", "b.dyndns.org", "c.dyndns.org", "d.dyndns.org"}
+	results, err := c.UpdateBatch(ctx, hosts, nil, 2)
+	if err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want DeadlineExceeded", err)
+	}
+	for i, r := range results {
+		if r.Hostname != hosts[i] || r.Err == nil {
+			t.Errorf("result %d = %+v, want a failure", i, r)
+		}
+	}
+	if !errors.Is(results[3].Err, context.DeadlineExceeded) {
+		t.Errorf("unsent hostname: err = %v", results[3].Err)
+	}
+}
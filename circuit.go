@@ -0,0 +1,136 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the service while the
+// Client's circuit breaker is open.
+var ErrCircuitOpen = errors.New("dyndns: circuit open after repeated service failures")
+
+// A CircuitState is the state of a Client's circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets requests through.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen fails requests with ErrCircuitOpen until the cooldown
+	// has passed.
+	CircuitOpen
+
+	// CircuitHalfOpen lets a single probe request through. Its success
+	// closes the circuit; a transient failure opens it again.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// WithCircuitBreaker makes the Client stop contacting the service after
+// threshold consecutive transient failures, as reported by IsTransient.
+// Requests then fail with ErrCircuitOpen until cooldown has passed, after
+// which a single probe request decides whether to close the circuit.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Client) error {
+		c.breaker.threshold, c.breaker.cooldown = threshold, cooldown
+		return nil
+	}
+}
+
+// CircuitState returns the state of the Client's circuit breaker.
+// It is always CircuitClosed without WithCircuitBreaker.
+func (c *Client) CircuitState() CircuitState {
+	return c.breaker.currentState()
+}
+
+// breaker is a circuit breaker. The zero value is disabled.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int       // consecutive transient failures while closed
+	opened   time.Time // when the circuit last opened
+	probing  bool      // whether a half-open probe is in flight
+}
+
+func (b *breaker) currentState() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && time.Since(b.opened) >= b.cooldown {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// allow returns ErrCircuitOpen if a request may not be sent now.
+func (b *breaker) allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.opened) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = CircuitHalfOpen
+	case CircuitHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+	}
+	b.probing = b.state == CircuitHalfOpen
+	return nil
+}
+
+// release ends a request allowed by allow without an outcome, for one
+// that was never sent.
+func (b *breaker) release() {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// report records the outcome of a request allowed by allow.
+func (b *breaker) report(err error) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return // says nothing about the service
+	}
+	failed := IsTransient(err)
+	switch {
+	case !failed:
+		b.state, b.failures = CircuitClosed, 0
+	case b.state == CircuitHalfOpen:
+		b.state, b.opened = CircuitOpen, time.Now()
+	default:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.state, b.opened = CircuitOpen, time.Now()
+		}
+	}
+}
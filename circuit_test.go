@@ -0,0 +1,137 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var n int
+	body := "911"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+	const cooldown = 50 * time.Millisecond
+	c, err := NewClient(srv.URL, AllowInsecure(), WithCircuitBreaker(3, cooldown))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	update := func() error {
+		_, err := c.Update(ctx, hostname, nil)
+		return err
+	}
+
+	// Consecutive transient failures open the circuit.
+	for i := 0; i < 3; i++ {
+		if c.CircuitState() != CircuitClosed {
+			t.Fatalf("after %d failures, state = %v", i, c.CircuitState())
+		}
+		if err := update(); err != Err911 {
+			t.Fatalf("err = %v, want 911", err)
+		}
+	}
+	if c.CircuitState() != CircuitOpen {
+		t.Fatalf("state = %v, want open", c.CircuitState())
+	}
+	if err := update(); err != ErrCircuitOpen || n != 3 {
+		t.Fatalf("open circuit: err = %v after %d requests", err, n)
+	}
+
+	// A failed probe opens the circuit again.
+	time.Sleep(cooldown)
+	if c.CircuitState() != CircuitHalfOpen {
+		t.Fatalf("after cooldown, state = %v", c.CircuitState())
+	}
+	if err := update(); err != Err911 || n != 4 {
+		t.Fatalf("probe: err = %v after %d requests", err, n)
+	}
+	if err := update(); err != ErrCircuitOpen {
+		t.Fatalf("after failed probe: err = %v", err)
+	}
+
+	// A successful probe closes it.
+	time.Sleep(cooldown)
+	body = "good 1.2.3.4"
+	if err := update(); err != nil {
+		t.Fatalf("probe: err = %v", err)
+	}
+	if c.CircuitState() != CircuitClosed {
+		t.Errorf("after successful probe, state = %v", c.CircuitState())
+	}
+}
+
+func TestCircuitBreakerIgnoresUnsent(t *testing.T) {
+	var n int
+	srv := newTestServer(t, "911", &n, nil)
+	const cooldown = 50 * time.Millisecond
+	creds := &rotatingCredentials{}
+	c, err := NewClient(srv.URL, AllowInsecure(), WithCircuitBreaker(2, cooldown), WithCredentials(creds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	update := func(credsErr error) error {
+		creds.err = credsErr
+		_, err := c.Update(ctx, hostname, nil)
+		return err
+	}
+	// A credentials failure between two transient failures does not
+	// reset the count.
+	update(nil)
+	if err := update(errors.New("no secret")); err == nil || n != 1 {
+		t.Fatalf("credentials failure: err = %v after %d requests", err, n)
+	}
+	update(nil)
+	if c.CircuitState() != CircuitOpen {
+		t.Fatalf("state = %v, want open", c.CircuitState())
+	}
+
+	// Nor does it use up the half-open probe.
+	time.Sleep(cooldown)
+	update(errors.New("no secret"))
+	if c.CircuitState() != CircuitHalfOpen {
+		t.Fatalf("after unsent probe, state = %v", c.CircuitState())
+	}
+	if err := update(nil); err != Err911 || n != 3 {
+		t.Errorf("probe: err = %v after %d requests", err, n)
+	}
+}
+
+func TestCircuitBreakerIgnoresFatal(t *testing.T) {
+	c, _ := NewClient(newTestClient(t, "badauth").URL, AllowInsecure(), WithCircuitBreaker(1, time.Hour))
+	for i := 0; i < 3; i++ {
+		if _, err := c.Update(context.Background(), hostname, nil); err != ErrAuth {
+			t.Fatalf("err = %v, want badauth", err)
+		}
+	}
+	if c.CircuitState() != CircuitClosed {
+		t.Errorf("state = %v after non-transient errors", c.CircuitState())
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<html>Bad Gateway</html>", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	_, err := (&Client{URL: srv.URL}).Update(context.Background(), hostname, nil)
+	if se, ok := err.(*StatusError); !ok || se.StatusCode != http.StatusBadGateway || !IsTransient(err) {
+		t.Errorf("err = %v, want transient StatusError 502", err)
+	}
+}
+
+func TestCircuitBreakerLeadingWhitespace(t *testing.T) {
+	c, _ := NewClient(newTestClient(t, "\ufeff\n good 1.2.3.4").URL, AllowInsecure(), WithCircuitBreaker(1, time.Hour))
+	c.Update(context.Background(), hostname, nil)
+	if s := c.CircuitState(); s != CircuitClosed {
+		t.Errorf("state = %v after a padded good response, want closed", s)
+	}
+}
@@ -0,0 +1,86 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+)
+
+// CLI environment variables, read by RunCLI for flags not given.
+const (
+	EnvUser     = "DYNDNS_USER"
+	EnvPassword = "DYNDNS_PASSWORD"
+	EnvHostname = "DYNDNS_HOSTNAME"
+	EnvURL      = "DYNDNS_URL"
+)
+
+// RunCLI implements a command-line updater. It parses args (without the
+// program name), sends a single update and writes the result to stdout.
+//
+//	-user, -password  account credentials
+//	-hostname         hostname to update
+//	-url              update service URL (default DynDNS)
+//	-ip               address to publish (default: detected by the service)
+//	-insecure         allow a service URL without HTTPS
+//
+// Flags that are not given are read from the DYNDNS_USER, DYNDNS_PASSWORD,
+// DYNDNS_HOSTNAME and DYNDNS_URL environment variables, which keeps secrets
+// out of shell history. Flags take precedence.
+func RunCLI(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("dyndns", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	var (
+		user     = fs.String("user", "", "account username ($"+EnvUser+")")
+		password = fs.String("password", "", "account password or update token ($"+EnvPassword+")")
+		hostname = fs.String("hostname", "", "hostname to update ($"+EnvHostname+")")
+		url      = fs.String("url", DynDNS, "update service URL ($"+EnvURL+")")
+		ipFlag   = fs.String("ip", "", "address to publish (default: detected by the service)")
+		insecure = fs.Bool("insecure", false, "allow a service URL without HTTPS")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for name, env := range map[string]struct {
+		v   *string
+		key string
+	}{
+		"user":     {user, EnvUser},
+		"password": {password, EnvPassword},
+		"hostname": {hostname, EnvHostname},
+		"url":      {url, EnvURL},
+	} {
+		if v := os.Getenv(env.key); v != "" && !set[name] {
+			*env.v = v
+		}
+	}
+	if *hostname == "" {
+		return errors.New("dyndns: no hostname given")
+	}
+	var ip net.IP
+	if *ipFlag != "" {
+		if ip = net.ParseIP(*ipFlag); ip == nil {
+			return fmt.Errorf("dyndns: invalid address %q", *ipFlag)
+		}
+	}
+
+	opts := []Option{WithCredentials(StaticCredentials(*user, *password))}
+	if *insecure {
+		opts = append(opts, AllowInsecure())
+	}
+	c, err := NewClient(*url, opts...)
+	if err != nil {
+		return err
+	}
+	res, err := c.Update(ctx, *hostname, ip)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, res.Code, res.IP)
+	return nil
+}
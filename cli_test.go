@@ -0,0 +1,53 @@
+package dyndns
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRunCLIEnv(t *testing.T) {
+	var user, pass, host string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ = r.BasicAuth()
+		host = r.URL.Query().Get("hostname")
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+	t.Setenv(EnvUser, "envuser")
+	t.Setenv(EnvPassword, "envpass")
+	t.Setenv(EnvHostname, "env.dyndns.org")
+	t.Setenv(EnvURL, srv.URL)
+
+	var out bytes.Buffer
+	if err := RunCLI(context.Background(), nil, &out); err != ErrInsecureURL {
+		t.Fatalf("without -insecure, err = %v", err)
+	}
+	if err := RunCLI(context.Background(), []string{"-insecure"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if user != "envuser" || pass != "envpass" || host != "env.dyndns.org" {
+		t.Errorf("from env: sent %q %q %q", user, pass, host)
+	}
+	if out.String() != "good 1.2.3.4\n" {
+		t.Errorf("output = %q", out.String())
+	}
+
+	err := RunCLI(context.Background(), []string{"-insecure", "-user", "flaguser", "-hostname", "flag.dyndns.org"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user != "flaguser" || pass != "envpass" || host != "flag.dyndns.org" {
+		t.Errorf("flags over env: sent %q %q %q", user, pass, host)
+	}
+}
+
+func TestRunCLINoHostname(t *testing.T) {
+	t.Setenv(EnvHostname, "")
+	if err := RunCLI(context.Background(), []string{"-user", "u"}, io.Discard); err == nil {
+		t.Error("expected error without hostname")
+	}
+}
@@ -0,0 +1,617 @@
+package dyndns
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// A Client sends requests to a dynamic DNS service on behalf of an account.
+// A Client is safe for concurrent use and should be reused, so that its
+// keep-alive connections to the service carry over between updates.
+type Client struct {
+	URL string
+
+	// Credentials supplies the account credentials for each update.
+	// If nil, requests are sent without authentication.
+	Credentials CredentialsProvider
+
+	// HTTPClient is used to send requests. If nil, http.DefaultClient is used.
+	// Transport options passed to NewClient apply only to the HTTPClient
+	// it creates.
+	HTTPClient *http.Client
+
+	// CheckIPURL is the service used by DetectIP. If empty, CheckIP is used.
+	CheckIPURL string
+
+	// CheckIPTTL is how long DetectIP reuses a detected address. If zero,
+	// DefaultCheckIPTTL is used; if negative, addresses are not reused.
+	CheckIPTTL time.Duration
+
+	// MinUpdateInterval is the time to wait after an update that changed
+	// nothing before updating the hostname again; see NextSafeUpdate.
+	// If zero, DefaultMinUpdateInterval is used.
+	MinUpdateInterval time.Duration
+
+	// FormatIP serializes addresses for the myip parameter.
+	// If nil, DefaultFormatIP is used.
+	FormatIP func(net.IP) string
+
+	// MaxResponseBytes limits the size of a response body. Larger bodies
+	// fail with ErrResponseTooLarge. If zero, DefaultMaxResponseBytes is used.
+	MaxResponseBytes int64
+
+	// MinInterval is the minimum time between any two update requests sent
+	// by the Client, whatever their hostnames, for providers that limit
+	// the request rate of an account. Updates wait for their turn unless
+	// RejectTooSoon is set. If zero, requests are not spaced.
+	MinInterval time.Duration
+
+	// OnChange, if non-nil, is called after a successful update that
+	// changed the address the Client knows for hostname. old is nil for
+	// a hostname the Client has not updated before.
+	OnChange func(hostname string, old, new net.IP)
+
+	// RefreshInterval, if positive, is how long after the last successful
+	// update ShouldUpdate asks to resend an unchanged address, for
+	// providers that expire hostnames that are never updated.
+	RefreshInterval time.Duration
+
+	// AbuseCooldown, if positive, is how long a hostname stays disabled
+	// after an abuse response. Once it passes, a single update is let
+	// through and the cooldown starts again: a successful response
+	// re-enables the hostname, any other leaves it disabled until the new
+	// cooldown passes. If zero, hostnames stay disabled until Enable or
+	// ResetAll.
+	AbuseCooldown time.Duration
+
+	// MaxClockSkew is how far the server's Date header may be from the
+	// local clock before results carry a ClockSkewError warning. If zero,
+	// DefaultMaxClockSkew is used; if negative, the clock is not checked.
+	MaxClockSkew time.Duration
+
+	// Resolver looks up the published addresses of hostnames for
+	// SyncHostname. If nil, net.DefaultResolver is used.
+	Resolver *net.Resolver
+
+	insecure      bool
+	requestLogger func(method, url string, headers http.Header)
+	successCodes  map[Code]bool
+	verify        bool
+	strictIP      bool
+	conditional   bool
+	trace         bool
+	header        http.Header
+	format        ResponseFormat
+	redundantIP   RedundantIPPolicy
+	breaker       breaker
+	counters      stats
+
+	credentialHints bool
+	keepHeaders     bool
+	noIDNA          bool
+	paramStyle      ParamStyle
+
+	attempts int
+	backoff  Backoff
+
+	rejectTooSoon bool
+	paceMu        sync.Mutex
+	nextRequest   time.Time
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+
+	detectMu        sync.Mutex
+	detected        net.IP
+	detectedExpires time.Time
+
+	transport     *http.Transport
+	transportOpts []func(*http.Transport)
+	pins          [][]byte
+	pinHost       string // the service URL's host, to which pins apply
+	wrappers      []func(http.RoundTripper) http.RoundTripper
+	dialer        net.Dialer
+	network       string
+	family        int
+
+	// dial replaces dialer.DialContext in tests.
+	dial func(ctx context.Context, d *net.Dialer, network, addr string) (net.Conn, error)
+
+	// interfaceAddrs replaces net.InterfaceByName and Addrs in tests.
+	interfaceAddrs func(name string) ([]net.Addr, error)
+}
+
+// DefaultMaxResponseBytes is the default limit on response body size.
+// Protocol responses are a single short line per hostname.
+const DefaultMaxResponseBytes = 4096
+
+// ErrResponseTooLarge is returned when a response body exceeds the
+// Client's MaxResponseBytes.
+var ErrResponseTooLarge = errors.New("dyndns: response body too large")
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// An UpdateResult describes the server's response to an update request.
+type UpdateResult struct {
+	Code Code
+	IP   net.IP // address echoed by the server, if any
+	Info string // raw text following the return code
+
+	// IPv4 and IPv6 are the echoed addresses of each family. A server
+	// confirming a dual-stack update may echo one of each, separated by
+	// whitespace or a comma; IP is then the first.
+	IPv4, IPv6 net.IP
+
+	// Header holds the HTTP response headers if the Client was created
+	// with KeepResponseHeaders. The Client never logs them.
+	Header http.Header
+
+	// Family is the address family, 4 or 6, the server detected the
+	// address from, for updates without an explicit address on a Client
+	// created with DetectFamily. Otherwise it is zero.
+	Family int
+
+	// PublishedFamily is the only address family, 4 or 6, published by a
+	// best-effort UpdateDual or UpdateFromInterfaces that found no address
+	// of the other. Otherwise it is zero.
+	PublishedFamily int
+
+	// RateLimit is the provider's rate-limit state, or nil if the
+	// response had no rate-limit headers.
+	RateLimit *RateLimit
+
+	// Timings is the breakdown of the request's duration,
+	// or nil if the Client was not created with WithTrace.
+	Timings *Timings
+
+	// Warnings lists problems that did not prevent the update,
+	// such as a failed VerifyWithCheckIP comparison.
+	Warnings []error
+}
+
+// A StatusError is returned for an HTTP error response that carries no
+// protocol return code.
+type StatusError struct {
+	StatusCode int
+}
+
+// Error satisfies the built-in error interface.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("dyndns: unexpected status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// firstLine returns the first line of body, after any leading byte order
+// mark and whitespace.
+func firstLine(body []byte) []byte {
+	body = bytes.TrimLeftFunc(bytes.TrimPrefix(body, utf8BOM), unicode.IsSpace)
+	if i := bytes.IndexByte(body, '\n'); i >= 0 {
+		return body[:i]
+	}
+	return body
+}
+
+// err returns the error for the result's code. If the server sent extra
+// text with an error code, the error is wrapped in a ResponseError.
+func (r UpdateResult) err() error {
+	err := r.Code.Err()
+	if err != nil && r.Info != "" {
+		err = &ResponseError{err, r.Info}
+	}
+	return err
+}
+
+// isSuccess reports whether code is a success, including the Client's
+// custom success codes.
+func (c *Client) isSuccess(code Code) bool {
+	return code.IsSuccess() || c.successCodes[code]
+}
+
+// Update sends a request to the service to change the hostname to ip.
+// If ip is nil, the update server will use the client's IP address.
+// Both good and nochg responses are successes and return a nil error;
+// other codes return the matching registered error.
+//
+// Unicode hostnames are converted to their ASCII (Punycode) form, and
+// hostnames that are not valid DNS names are rejected, unless the Client
+// was created with DisableIDNA.
+//
+// After an abuse response, further updates for the hostname fail with
+// ErrHostDisabled without contacting the service, until AbuseCooldown
+// passes if it is set.
+//
+// If the Client was created with WithRetry, transient failures are retried.
+func (c *Client) Update(ctx context.Context, hostname string, ip net.IP, opts ...UpdateOption) (UpdateResult, error) {
+	hostname, err := c.normalizeHostname(hostname)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	if c.isDisabled(hostname) {
+		return UpdateResult{}, ErrHostDisabled
+	}
+	params, err := newUpdateParams(opts)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	if ip != nil && len(params.extraIPs) == 0 && c.redundantIP != SendRedundantIP {
+		if _, last := c.lastUpdate(hostname); ip.Equal(last) {
+			if c.redundantIP == SkipRedundantIP {
+				return UpdateResult{Code: CodeNoChange, IP: last}, nil
+			}
+			ip = nil
+		}
+	}
+	res, err := c.send(ctx, hostname, ip, params)
+	for attempt := 1; attempt < c.attempts && IsTransient(err); attempt++ {
+		delay := c.backoff.NextDelay(attempt)
+		var rl *RateLimitedError
+		if errors.As(err, &rl) && rl.RetryAfter > delay {
+			delay = rl.RetryAfter
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return res, err
+		}
+		res, err = c.send(ctx, hostname, ip, params)
+	}
+	return res, err
+}
+
+// UpdateIPs publishes ips together as the addresses of hostname, for
+// example as a round-robin set, in one request whose myip parameter lists
+// them separated by commas. Support for several addresses is
+// provider-dependent. A single address is the same as Update.
+func (c *Client) UpdateIPs(ctx context.Context, hostname string, ips []net.IP, opts ...UpdateOption) (UpdateResult, error) {
+	if len(ips) == 0 {
+		return UpdateResult{}, errors.New("dyndns: no addresses to publish")
+	}
+	if len(ips) > 1 {
+		opts = append(opts[:len(opts):len(opts)], func(p *updateParams) error {
+			p.extraIPs = ips[1:]
+			return nil
+		})
+	}
+	return c.Update(ctx, hostname, ips[0], opts...)
+}
+
+// send makes a single update request.
+func (c *Client) send(ctx context.Context, hostname string, ip net.IP, params *updateParams) (UpdateResult, error) {
+	var last net.IP
+	if c.conditional && len(params.extraIPs) == 0 && !params.mail {
+		// A 304 keeps the last address, so only ask for one when
+		// that is the address being sent.
+		t, prev := c.lastUpdate(hostname)
+		if !t.IsZero() && (ip == nil || ip.Equal(prev)) {
+			last = prev
+			params.header.Set("If-Modified-Since", t.UTC().Format(http.TimeFormat))
+		}
+	}
+	if err := c.pace(ctx); err != nil {
+		return UpdateResult{}, err
+	}
+	tctx, tr := c.withTrace(ctx)
+	resp, body, err := c.do(tctx, hostname, ip, params)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		res := UpdateResult{Code: CodeNoChange, IP: last, Timings: tr.done()}
+		return c.finish(ctx, hostname, ip, params, res)
+	}
+	res := c.parse(body)
+	res.RateLimit = parseRateLimit(resp.Header, time.Now())
+	res.Timings = tr.done()
+	if c.keepHeaders {
+		res.Header = resp.Header
+	}
+	if w := c.clockSkew(resp.Header, time.Now()); w != nil {
+		res.Warnings = append(res.Warnings, w)
+	}
+	return c.finish(ctx, hostname, ip, params, res)
+}
+
+// do sends an update request for hostname, which may be a comma-separated
+// list, and returns the response with its body read and closed. Requests
+// pass through the circuit breaker, which learns only the outcome of those
+// actually sent. A 429 response is a RateLimitedError,
+// and a 5xx response without a protocol return code is a StatusError.
+func (c *Client) do(ctx context.Context, hostname string, ip net.IP, params *updateParams) (*http.Response, []byte, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, nil, err
+	}
+	req, err := c.newRequest(ctx, hostname, ip, params)
+	if err != nil {
+		c.breaker.release() // the service was not contacted
+		c.counters.request(err)
+		return nil, nil, err
+	}
+	if err := c.takeProbes(hostname); err != nil {
+		c.breaker.release()
+		return nil, nil, err
+	}
+	resp, body, err := c.roundTrip(ctx, req)
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		err = &RateLimitedError{parseRetryAfter(resp.Header, time.Now())}
+	}
+	if err == nil && resp.StatusCode >= 500 {
+		if code := c.parse(firstLine(body)).Code; !code.known() && !c.successCodes[code] {
+			err = &StatusError{resp.StatusCode}
+		}
+	}
+	failure := err
+	if code := c.parse(firstLine(body)).Code; err == nil && !c.isSuccess(code) {
+		failure = code.Err()
+	}
+	c.breaker.report(failure)
+	c.counters.request(err)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+// newRequest prepares the HTTP request for do.
+func (c *Client) newRequest(ctx context.Context, hostname string, ip net.IP, params *updateParams) (*http.Request, error) {
+	query := params.query
+	if ip != nil {
+		myip := c.formatIP(ip)
+		for _, extra := range params.extraIPs {
+			myip += "," + c.formatIP(extra)
+		}
+		query.Set("myip", myip)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.requestURL(hostname, query), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.Credentials != nil {
+		user, password, err := c.Credentials.Credentials(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("dyndns: credentials: %w", err)
+		}
+		if c.credentialHints && strings.Contains(user, "@") && !slices.Contains(params.warnings, ErrLoginCredentials) {
+			params.warnings = append(params.warnings, ErrLoginCredentials)
+		}
+		req.SetBasicAuth(user, password)
+	}
+	c.setHeaders(req)
+	for k, v := range params.header {
+		req.Header[k] = v
+	}
+
+	if c.requestLogger != nil {
+		c.logRequest(req)
+	}
+	return req, nil
+}
+
+// roundTrip sends req and reads the response body.
+func (c *Client) roundTrip(ctx context.Context, req *http.Request) (*http.Response, []byte, error) {
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := c.readBody(ctx, resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+// finish records the result of updating hostname to ip and applies the
+// Client's checks to it.
+func (c *Client) finish(ctx context.Context, hostname string, ip net.IP, params *updateParams, res UpdateResult) (UpdateResult, error) {
+	if ip == nil && !params.mail {
+		res.Family = c.family
+	}
+	res.Warnings = append(res.Warnings, params.warnings...)
+	c.counters.result(c.statsCode(res.Code))
+	if old, changed := c.record(hostname, ip, res); changed {
+		_, cur := c.lastUpdate(hostname)
+		switch {
+		case params.changes != nil:
+			*params.changes = append(*params.changes, hostChange{hostname, old, cur})
+		case c.OnChange != nil:
+			c.OnChange(hostname, old, cur)
+		}
+	}
+	if ip != nil && res.Code == CodeGood && res.IP != nil && !res.IP.Equal(ip) {
+		mismatch := &IPMismatchError{Sent: ip, Recorded: res.IP}
+		if c.strictIP {
+			return res, mismatch
+		}
+		res.Warnings = append(res.Warnings, mismatch)
+	}
+	if c.verify && c.isSuccess(res.Code) && !params.mail {
+		published := res.IP
+		if published == nil {
+			published = ip
+		}
+		if w := c.verifyIP(ctx, published); w != nil {
+			res.Warnings = append(res.Warnings, w)
+		}
+	}
+	if c.successCodes[res.Code] {
+		return res, nil
+	}
+	return res, res.err()
+}
+
+// ErrIPMismatch matches an IPMismatchError with errors.Is.
+var ErrIPMismatch = errors.New("dyndns: server recorded a different address than sent")
+
+// An IPMismatchError reports that the server echoed a different address
+// on a good response than the one sent with myip. By default it is added
+// to UpdateResult.Warnings; with FailOnIPMismatch it is returned.
+type IPMismatchError struct {
+	Sent, Recorded net.IP
+}
+
+// Error satisfies the built-in error interface.
+func (e *IPMismatchError) Error() string {
+	return fmt.Sprintf("%v: sent %s, recorded %s", ErrIPMismatch, e.Sent, e.Recorded)
+}
+
+// Is reports whether target is ErrIPMismatch.
+func (e *IPMismatchError) Is(target error) bool {
+	return target == ErrIPMismatch
+}
+
+// verifyIP compares published with the address reported by DetectIP.
+func (c *Client) verifyIP(ctx context.Context, published net.IP) error {
+	detected, err := c.DetectIP(ctx)
+	if err != nil {
+		return fmt.Errorf("dyndns: verify: %w", err)
+	}
+	if published != nil && !published.Equal(detected) {
+		return fmt.Errorf("%w: published %s, detected %s", ErrVerifyMismatch, published, detected)
+	}
+	return nil
+}
+
+// setHeaders adds the headers sent with every request to the service:
+// those given to WithHeader and, unless one was given, the User-Agent.
+func (c *Client) setHeaders(req *http.Request) {
+	for k, v := range c.header {
+		req.Header[k] = append(req.Header[k], v...)
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", UserAgent)
+	}
+}
+
+// DefaultFormatIP formats ip for an update request. IPv4-mapped IPv6
+// addresses are written as plain IPv4. A net.IP carries no zone, so
+// link-local addresses are written without one.
+func DefaultFormatIP(ip net.IP) string {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return ip.String()
+	}
+	return addr.Unmap().String()
+}
+
+func (c *Client) formatIP(ip net.IP) string {
+	if c.FormatIP != nil {
+		return c.FormatIP(ip)
+	}
+	return DefaultFormatIP(ip)
+}
+
+// readBody reads r up to the Client's response size limit. The body of a
+// request made with ctx stops reading when ctx is done; the error is then
+// reported as ctx.Err() rather than as the transport's read error.
+func (c *Client) readBody(ctx context.Context, r io.Reader) ([]byte, error) {
+	max := c.MaxResponseBytes
+	if max <= 0 {
+		max = DefaultMaxResponseBytes
+	}
+	body, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if int64(len(body)) > max {
+		return nil, ErrResponseTooLarge
+	}
+	return body, nil
+}
+
+// parseResponse reads a return code and optional value from r. For
+// codes that success reports as successes, the value is the IP address.
+func parseResponse(r io.Reader, success func(Code) bool) UpdateResult {
+	// The code is the first word and the value the rest, separated by any
+	// run of whitespace, as some servers use tabs or several spaces. A bare
+	// code needs no delimiter: the body simply ends. If reading fails, only
+	// the code is kept, since the value may be cut short.
+	body, err := io.ReadAll(r)
+	s := strings.TrimSpace(string(body))
+	code, info := s, ""
+	if i := strings.IndexFunc(s, unicode.IsSpace); i >= 0 {
+		code, info = s[:i], strings.TrimSpace(s[i:])
+	}
+	if err != nil {
+		return UpdateResult{Code: Code(code)}
+	}
+	res := UpdateResult{Code: Code(code), Info: info}
+	if success(res.Code) {
+		res.setIPs(res.Info)
+	}
+	return res
+}
+
+// setIPs sets the result's addresses from s, which holds one address or
+// several separated by whitespace or commas. Nothing is set unless every
+// field is an address.
+func (r *UpdateResult) setIPs(s string) {
+	fields := strings.FieldsFunc(s, func(c rune) bool {
+		return c == ',' || unicode.IsSpace(c)
+	})
+	ips := make([]net.IP, len(fields))
+	for i, f := range fields {
+		if ips[i] = net.ParseIP(f); ips[i] == nil {
+			return
+		}
+	}
+	for _, ip := range ips {
+		if r.IP == nil {
+			r.IP = ip
+		}
+		if ipFamily(ip) == 4 && r.IPv4 == nil {
+			r.IPv4 = ip
+		} else if ipFamily(ip) == 6 && r.IPv6 == nil {
+			r.IPv6 = ip
+		}
+	}
+}
+
+// Ping checks that the service is reachable without sending an update.
+// It issues an unauthenticated HEAD request to the service URL, falling back
+// to GET if the server does not allow HEAD. A 2xx or 401 status means the
+// server is alive; transport failures and other statuses are errors.
+func (c *Client) Ping(ctx context.Context) error {
+	status, err := c.probe(ctx, "HEAD")
+	if err == nil && status == http.StatusMethodNotAllowed {
+		status, err = c.probe(ctx, "GET")
+	}
+	if err != nil {
+		return err
+	}
+	if status/100 == 2 || status == http.StatusUnauthorized {
+		return nil
+	}
+	return fmt.Errorf("dyndns: ping: unexpected status %d %s", status, http.StatusText(status))
+}
+
+func (c *Client) probe(ctx context.Context, method string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.URL, nil)
+	if err != nil {
+		return 0, err
+	}
+	c.setHeaders(req)
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, DefaultMaxResponseBytes))
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,379 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPing(t *testing.T) {
+	for _, tt := range []struct {
+		status int
+		ok     bool
+	}{
+		{http.StatusOK, true},
+		{http.StatusUnauthorized, true},
+		{http.StatusNotFound, false},
+		{http.StatusServiceUnavailable, false},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "HEAD" {
+				t.Errorf("method = %s, want HEAD", r.Method)
+			}
+			if r.Header.Get("Authorization") != "" {
+				t.Error("ping sent credentials")
+			}
+			w.WriteHeader(tt.status)
+		}))
+		c := &Client{URL: srv.URL, Credentials: StaticCredentials(username, password)}
+		err := c.Ping(context.Background())
+		if (err == nil) != tt.ok {
+			t.Errorf("status %d: got error %v", tt.status, err)
+		}
+		srv.Close()
+	}
+}
+
+func TestPingFallbackGET(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer srv.Close()
+	c := &Client{URL: srv.URL}
+	if err := c.Ping(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPingUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	c := &Client{URL: srv.URL}
+	if err := c.Ping(context.Background()); err == nil {
+		t.Error("expected error for closed server")
+	}
+}
+
+// newTestServer returns a test server that replies to every request with
+// body. If n is not nil, it counts the requests in *n; if q is not nil, it
+// stores the query of the last request in *q.
+func newTestServer(t *testing.T, body string, n *int, q *url.Values) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n != nil {
+			*n++
+		}
+		if q != nil {
+			*q = r.URL.Query()
+		}
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTestClient returns a Client for a test server that replies with body.
+func newTestClient(t *testing.T, body string) *Client {
+	srv := newTestServer(t, body, nil, nil)
+	return &Client{URL: srv.URL, Credentials: StaticCredentials(username, password)}
+}
+
+func TestUpdateCodes(t *testing.T) {
+	for _, tt := range []struct {
+		body string
+		code Code
+		ip   net.IP
+		err  error
+	}{
+		{"good 1.2.3.4", CodeGood, net.IPv4(1, 2, 3, 4), nil},
+		{"nochg 1.2.3.4\n", CodeNoChange, net.IPv4(1, 2, 3, 4), nil},
+		{"badauth", CodeBadAuth, nil, ErrAuth},
+		{"notfqdn", CodeNotFQDN, nil, ErrDomain},
+		{"911", Code911, nil, Err911},
+		{"!yours", CodeNotYours, nil, ErrNotYours},
+		{"!active\n", CodeNotActive, nil, ErrNotActive},
+	} {
+		res, err := newTestClient(t, tt.body).Update(context.Background(), hostname, nil)
+		if res.Code != tt.code || !res.IP.Equal(tt.ip) || err != tt.err {
+			t.Errorf("%q: got %v, %v; want %v %v, %v", tt.body, res, err, tt.code, tt.ip, tt.err)
+		}
+	}
+}
+
+func TestParseResponseWhitespace(t *testing.T) {
+	for _, body := range []string{"good 1.2.3.4", "good\t1.2.3.4", "good    1.2.3.4", "good\t \t1.2.3.4\n"} {
+		res, err := newTestClient(t, body).Update(context.Background(), hostname, nil)
+		if err != nil || res.Code != CodeGood || !res.IP.Equal(net.IPv4(1, 2, 3, 4)) {
+			t.Errorf("%q: got %q %v, %v", body, res.Code, res.IP, err)
+		}
+	}
+}
+
+func TestParseResponseEOF(t *testing.T) {
+	for _, tt := range []struct {
+		body string
+		code Code
+		info string
+	}{
+		{"", "", ""},
+		{"badauth", CodeBadAuth, ""},
+		{"good ", CodeGood, ""},
+		{"good 1.2.3.4", CodeGood, "1.2.3.4"},
+		{"abuse blocked for now\n", CodeAbuse, "blocked for now"},
+		{"good\t1.2.3.4", CodeGood, "1.2.3.4"},
+		{"good   1.2.3.4", CodeGood, "1.2.3.4"},
+		{"good \t 1.2.3.4\r\n", CodeGood, "1.2.3.4"},
+		{"badagent\tno user agent", CodeBadAgent, "no user agent"},
+	} {
+		res := parseResponse(strings.NewReader(tt.body), Code.IsSuccess)
+		if res.Code != tt.code || res.Info != tt.info {
+			t.Errorf("%q: got %q, %q; want %q, %q", tt.body, res.Code, res.Info, tt.code, tt.info)
+		}
+	}
+}
+
+func TestParseDualIPs(t *testing.T) {
+	v4, v6 := net.IPv4(1, 2, 3, 4), net.ParseIP("2001:db8::1")
+	for _, tt := range []struct {
+		body         string
+		ip, ip4, ip6 net.IP
+	}{
+		{"good 1.2.3.4", v4, v4, nil},
+		{"good 2001:db8::1", v6, nil, v6},
+		{"good 1.2.3.4 2001:db8::1", v4, v4, v6},
+		{"nochg 2001:db8::1,1.2.3.4", v6, v4, v6},
+		{"good 1.2.3.4, 2001:db8::1\n", v4, v4, v6},
+		{"good 1.2.3.4 extra", nil, nil, nil},
+	} {
+		res := parseResponse(strings.NewReader(tt.body), Code.IsSuccess)
+		if !res.IP.Equal(tt.ip) || !res.IPv4.Equal(tt.ip4) || !res.IPv6.Equal(tt.ip6) {
+			t.Errorf("%q: got %v, %v, %v; want %v, %v, %v", tt.body, res.IP, res.IPv4, res.IPv6, tt.ip, tt.ip4, tt.ip6)
+		}
+	}
+}
+
+func TestUpdateSlowBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "12")
+		for _, b := range "good 1.2.3.4" {
+			fmt.Fprintf(w, "%c", b)
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}))
+	defer srv.Close()
+	c := &Client{URL: srv.URL}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.Update(ctx, hostname, nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > 400*time.Millisecond {
+		t.Errorf("Update returned after %v", d)
+	}
+
+	res, err := c.Update(context.Background(), hostname, nil)
+	if err != nil || res.Code != CodeGood {
+		t.Errorf("without deadline: got %v, %v", res, err)
+	}
+}
+
+func TestUpdateInvalidCode(t *testing.T) {
+	res, err := newTestClient(t, "bogus").Update(context.Background(), hostname, nil)
+	if res.Code.IsSuccess() || err == nil {
+		t.Errorf("got %v, %v", res, err)
+	}
+}
+
+func TestCodeIsSuccess(t *testing.T) {
+	for code, want := range map[Code]bool{
+		CodeGood:     true,
+		CodeNoChange: true,
+		CodeAbuse:    false,
+		Code911:      false,
+		"bogus":      false,
+	} {
+		if code.IsSuccess() != want {
+			t.Errorf("%q.IsSuccess() = %t", code, !want)
+		}
+	}
+}
+
+type rotatingCredentials struct {
+	n   int
+	err error
+}
+
+func (r *rotatingCredentials) Credentials(context.Context) (string, string, error) {
+	r.n++
+	return username, fmt.Sprint(password, r.n), r.err
+}
+
+func TestCredentialsProvider(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pass, _ := r.BasicAuth()
+		got = append(got, pass)
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+	creds := &rotatingCredentials{}
+	c := &Client{URL: srv.URL, Credentials: creds}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Update(context.Background(), hostname, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(got) != 2 || got[0] == got[1] {
+		t.Errorf("passwords = %q, want two distinct values", got)
+	}
+
+	creds.err = errors.New("vault sealed")
+	if _, err := c.Update(context.Background(), hostname, nil); !errors.Is(err, creds.err) {
+		t.Errorf("err = %v, want %v", err, creds.err)
+	}
+	if len(got) != 2 {
+		t.Error("request sent despite credentials error")
+	}
+}
+
+func TestUpdateErrorDetail(t *testing.T) {
+	res, err := newTestClient(t, "abuse blocked until 2038").Update(context.Background(), hostname, nil)
+	var re *ResponseError
+	if !errors.As(err, &re) || re.Detail != "blocked until 2038" {
+		t.Fatalf("err = %v, want ResponseError with detail", err)
+	}
+	if !errors.Is(err, ErrAbuse) {
+		t.Errorf("err = %v, want wrapped ErrAbuse", err)
+	}
+	if res.Info != re.Detail {
+		t.Errorf("Info = %q, want %q", res.Info, re.Detail)
+	}
+}
+
+func TestBadAgentDetail(t *testing.T) {
+	for _, detail := range []string{"no user agent", "http method not allowed"} {
+		_, err := newTestClient(t, "badagent "+detail+"\n").Update(context.Background(), hostname, nil)
+		var re *ResponseError
+		if !errors.As(err, &re) || re.Detail != detail || !errors.Is(err, ErrAgent) {
+			t.Errorf("%q: err = %v, want ErrAgent with detail", detail, err)
+		}
+		if want := ErrAgent.Error() + " (" + detail + ")"; err.Error() != want {
+			t.Errorf("%q: message %q, want %q", detail, err, want)
+		}
+	}
+	if _, err := newTestClient(t, "badagent").Update(context.Background(), hostname, nil); err != ErrAgent {
+		t.Errorf("without detail: err = %v, want ErrAgent", err)
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	c := newTestClient(t, "good 1.2.3.4"+strings.Repeat(" ", DefaultMaxResponseBytes))
+	if _, err := c.Update(context.Background(), hostname, nil); err != ErrResponseTooLarge {
+		t.Errorf("err = %v, want ErrResponseTooLarge", err)
+	}
+	c.MaxResponseBytes = 2 * DefaultMaxResponseBytes
+	if _, err := c.Update(context.Background(), hostname, nil); err != nil {
+		t.Errorf("with larger limit, err = %v", err)
+	}
+}
+
+func TestIPMismatch(t *testing.T) {
+	sent := net.IPv4(5, 6, 7, 8)
+	srv := newTestServer(t, "good 1.2.3.4", nil, nil)
+
+	c, _ := NewClient(srv.URL, AllowInsecure())
+	res, err := c.Update(context.Background(), hostname, sent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mismatch *IPMismatchError
+	if len(res.Warnings) != 1 || !errors.As(res.Warnings[0], &mismatch) {
+		t.Fatalf("warnings = %v, want one IPMismatchError", res.Warnings)
+	}
+	if !mismatch.Sent.Equal(sent) || !mismatch.Recorded.Equal(net.IPv4(1, 2, 3, 4)) {
+		t.Errorf("mismatch = %+v", mismatch)
+	}
+
+	c, _ = NewClient(srv.URL, AllowInsecure(), FailOnIPMismatch())
+	if _, err := c.Update(context.Background(), hostname, sent); !errors.Is(err, ErrIPMismatch) {
+		t.Errorf("strict: err = %v, want ErrIPMismatch", err)
+	}
+	if _, err := c.Update(context.Background(), hostname, net.IPv4(1, 2, 3, 4)); err != nil {
+		t.Errorf("matching address: err = %v", err)
+	}
+}
+
+func TestCheckCredentialsRetry(t *testing.T) {
+	var n int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n++; n < 3 {
+			io.WriteString(w, "911")
+			return
+		}
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+	c, err := NewClient(srv.URL, AllowInsecure(), CheckCredentials(),
+		WithCredentials(StaticCredentials("me@example.org", password)),
+		WithRetry(3, &ExponentialBackoff{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Update(context.Background(), hostname, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0] != ErrLoginCredentials {
+		t.Errorf("after %d attempts, warnings = %v", n, res.Warnings)
+	}
+}
+
+func TestCheckCredentials(t *testing.T) {
+	srv := newTestServer(t, "good 1.2.3.4", nil, nil)
+	for _, tt := range []struct {
+		user  string
+		check bool
+		warn  bool
+	}{
+		{"me@example.org", true, true},
+		{"me", true, false},
+		{"me@example.org", false, false},
+	} {
+		opts := []Option{AllowInsecure(), WithCredentials(StaticCredentials(tt.user, password))}
+		if tt.check {
+			opts = append(opts, CheckCredentials())
+		}
+		c, err := NewClient(srv.URL, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := c.Update(context.Background(), hostname, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var warned bool
+		for _, w := range res.Warnings {
+			warned = warned || w == ErrLoginCredentials
+		}
+		if warned != tt.warn {
+			t.Errorf("user %q, check %t: warnings = %v", tt.user, tt.check, res.Warnings)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+)
+
+// A CredentialsProvider supplies account credentials. Client calls it for
+// every request, so rotated secrets take effect without rebuilding the Client.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (user, password string, err error)
+}
+
+// StaticCredentials returns a CredentialsProvider that always returns
+// the given username and password.
+func StaticCredentials(user, password string) CredentialsProvider {
+	return staticCredentials{user, password}
+}
+
+type staticCredentials struct {
+	user, password string
+}
+
+func (s staticCredentials) Credentials(context.Context) (string, string, error) {
+	return s.user, s.password, nil
+}
+
+// ErrLoginCredentials is added to UpdateResult.Warnings by a Client created
+// with CheckCredentials when the username looks like an email address.
+// Many providers, including DynDNS and No-IP, expect an update-specific
+// username or token rather than the web login, and reply badauth otherwise.
+var ErrLoginCredentials = errors.New("dyndns: username looks like a web login; " +
+	"the provider may require an update-specific username or token")
+
+// CheckCredentials makes the Client warn about credentials that look like
+// a web login instead of update credentials. Since some accounts do use an
+// email address as the update username, the check is opt-in.
+func CheckCredentials() Option {
+	return func(c *Client) error {
+		c.credentialHints = true
+		return nil
+	}
+}
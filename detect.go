@@ -0,0 +1,139 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// CheckIP is the default service queried by DetectIP.
+const CheckIP = "http://checkip.dyndns.com/"
+
+// ErrVerifyMismatch is reported as a warning by VerifyWithCheckIP when the
+// published address differs from the detected one.
+var ErrVerifyMismatch = errors.New("dyndns: published address does not match detected address")
+
+// DefaultCheckIPTTL is the default for Client.CheckIPTTL.
+const DefaultCheckIPTTL = 30 * time.Second
+
+// DetectIP asks the Client's CheckIPURL service for the public IP address
+// of the requests it receives. Transport options such as WithDialNetwork
+// apply, so the detected address family follows the dial network.
+//
+// A detected address is reused for CheckIPTTL to avoid hammering the
+// service; use ForceDetectIP to bypass the cache.
+func (c *Client) DetectIP(ctx context.Context) (net.IP, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.detectMu.Lock()
+	ip, expires := c.detected, c.detectedExpires
+	c.detectMu.Unlock()
+	if ip != nil && time.Now().Before(expires) {
+		return ip, nil
+	}
+	return c.ForceDetectIP(ctx)
+}
+
+// ForceDetectIP is like DetectIP but always queries the service,
+// refreshing the cached address.
+func (c *Client) ForceDetectIP(ctx context.Context) (net.IP, error) {
+	ip, err := c.queryCheckIP(ctx, c.httpClient())
+	if err != nil {
+		return nil, err
+	}
+	ttl := c.CheckIPTTL
+	if ttl == 0 {
+		ttl = DefaultCheckIPTTL
+	}
+	c.detectMu.Lock()
+	c.detected, c.detectedExpires = ip, time.Now().Add(ttl)
+	c.detectMu.Unlock()
+	return ip, nil
+}
+
+func (c *Client) queryCheckIP(ctx context.Context, client *http.Client) (net.IP, error) {
+	url := c.CheckIPURL
+	if url == "" {
+		url = CheckIP
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("User-Agent", UserAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("dyndns: checkip: unexpected status %s", resp.Status)
+	}
+	body, err := c.readBody(ctx, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if ip := findIP(string(body)); ip != nil {
+		return ip, nil
+	}
+	return nil, fmt.Errorf("dyndns: checkip: no address in response")
+}
+
+// Defaults for DetectIPViaDNS. Google's authoritative servers answer a TXT
+// query for DNSCheckName with the address the query came from.
+var (
+	DNSCheckName   = "o-o.myaddr.l.google.com"
+	DNSCheckServer = "ns1.google.com:53"
+)
+
+// NewDNSResolver returns a Resolver that sends all queries to server,
+// given as host:port, instead of the system's configured resolvers.
+func NewDNSResolver(server string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// DetectIPViaDNS finds the public IP address by looking up the TXT record
+// DNSCheckName, whose value is the address of the querying client. Since the
+// answer depends on who asks, resolver must query the authoritative server
+// directly; if nil, NewDNSResolver(DNSCheckServer) is used.
+func DetectIPViaDNS(ctx context.Context, resolver *net.Resolver) (net.IP, error) {
+	if resolver == nil {
+		resolver = NewDNSResolver(DNSCheckServer)
+	}
+	txts, err := resolver.LookupTXT(ctx, DNSCheckName)
+	if err != nil {
+		return nil, err
+	}
+	for _, txt := range txts {
+		if ip := net.ParseIP(strings.TrimSpace(txt)); ip != nil {
+			return ip, nil
+		}
+	}
+	return nil, fmt.Errorf("dyndns: no address in TXT records for %s", DNSCheckName)
+}
+
+// findIP returns the first IP address in s, which may be plain text or
+// an HTML page such as the one served by checkip.dyndns.com.
+func findIP(s string) net.IP {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.Is(unicode.ASCII_Hex_Digit, r) && r != '.' && r != ':'
+	})
+	for _, f := range fields {
+		if ip := net.ParseIP(f); ip != nil {
+			return ip
+		}
+	}
+	return nil
+}
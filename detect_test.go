@@ -0,0 +1,146 @@
+package dyndns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+const checkIPPage = "<html><head><title>Current IP Check</title></head>" +
+	"<body>Current IP Address: 5.6.7.8</body></html>\r\n"
+
+func TestDetectIP(t *testing.T) {
+	srv := newTestServer(t, checkIPPage, nil, nil)
+	c := &Client{CheckIPURL: srv.URL}
+	ip, err := c.DetectIP(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ip.Equal(net.IPv4(5, 6, 7, 8)) {
+		t.Errorf("ip = %v, want 5.6.7.8", ip)
+	}
+}
+
+func TestFindIP(t *testing.T) {
+	for s, want := range map[string]net.IP{
+		"1.2.3.4\n":          net.IPv4(1, 2, 3, 4),
+		"addr: 2001:db8::1":  net.ParseIP("2001:db8::1"),
+		checkIPPage:          net.IPv4(5, 6, 7, 8),
+		"<html>none</html>":  nil,
+		"Current IP: bogus.": nil,
+	} {
+		if got := findIP(s); !got.Equal(want) {
+			t.Errorf("findIP(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestVerifyWithCheckIP(t *testing.T) {
+	for _, tt := range []struct {
+		body     string
+		mismatch bool
+	}{
+		{"good 5.6.7.8", false},
+		{"good 1.2.3.4", true},
+	} {
+		srv := newTestServer(t, tt.body, nil, nil)
+		c, err := NewClient(srv.URL, AllowInsecure(), VerifyWithCheckIP())
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.CheckIPURL = newTestServer(t, checkIPPage, nil, nil).URL
+		res, err := c.Update(context.Background(), hostname, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var mismatch bool
+		for _, w := range res.Warnings {
+			mismatch = mismatch || errors.Is(w, ErrVerifyMismatch)
+		}
+		if mismatch != tt.mismatch {
+			t.Errorf("%q: warnings = %v, want mismatch %t", tt.body, res.Warnings, tt.mismatch)
+		}
+	}
+}
+
+// serveTXT answers every DNS query received on conn with a TXT record
+// holding each of txts.
+func serveTXT(conn net.PacketConn, txts ...string) {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		q := buf[:n]
+		end := 12 // end of the question section
+		for q[end] != 0 {
+			end += int(q[end]) + 1
+		}
+		end += 5 // root label, type, class
+
+		msg := append([]byte(nil), q[:end]...)
+		msg[2], msg[3] = 0x84, 0x00 // response, authoritative
+		binary.BigEndian.PutUint16(msg[6:], uint16(len(txts)))
+		for _, txt := range txts {
+			msg = append(msg, 0xc0, 12, 0, 16, 0, 1, 0, 0, 0, 60)
+			msg = binary.BigEndian.AppendUint16(msg, uint16(len(txt)+1))
+			msg = append(msg, byte(len(txt)))
+			msg = append(msg, txt...)
+		}
+		conn.WriteTo(msg, addr)
+	}
+}
+
+func TestDetectIPViaDNS(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go serveTXT(conn, "edns0-client-subnet 5.6.7.0/24", "5.6.7.8")
+
+	ip, err := DetectIPViaDNS(context.Background(), NewDNSResolver(conn.LocalAddr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ip.Equal(net.IPv4(5, 6, 7, 8)) {
+		t.Errorf("ip = %v, want 5.6.7.8", ip)
+	}
+}
+
+func TestDetectIPCache(t *testing.T) {
+	var n int
+	srv := newTestServer(t, "5.6.7.8", &n, nil)
+	c := &Client{CheckIPURL: srv.URL}
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if ip, err := c.DetectIP(ctx); err != nil || !ip.Equal(net.IPv4(5, 6, 7, 8)) {
+			t.Fatalf("DetectIP = %v, %v", ip, err)
+		}
+	}
+	if n != 1 {
+		t.Errorf("%d checkip requests within TTL, want 1", n)
+	}
+	if _, err := c.ForceDetectIP(ctx); err != nil || n != 2 {
+		t.Errorf("ForceDetectIP: err %v, %d requests, want 2", err, n)
+	}
+
+	c.CheckIPTTL = -1
+	c.ForceDetectIP(ctx)
+	c.DetectIP(ctx)
+	if n != 4 {
+		t.Errorf("with negative TTL, %d requests, want 4", n)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	c.CheckIPTTL = time.Hour
+	c.ForceDetectIP(ctx)
+	if _, err := c.DetectIP(canceled); err != context.Canceled {
+		t.Errorf("canceled context: err = %v", err)
+	}
+}
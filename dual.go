@@ -0,0 +1,120 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// A FamilyError reports that no address of an address family, 4 or 6,
+// could be found for a dual-stack update.
+type FamilyError struct {
+	Family int // the missing family
+	Err    error
+}
+
+// Error satisfies the built-in error interface.
+func (e *FamilyError) Error() string {
+	return fmt.Sprintf("dyndns: no IPv%d address: %v", e.Family, e.Err)
+}
+
+// Unwrap returns the error from detecting an address of the family.
+func (e *FamilyError) Unwrap() error {
+	return e.Err
+}
+
+// BestEffort lets UpdateDual publish the addresses it found when one
+// family is missing, instead of failing. The missing family is reported
+// as a FamilyError in the result's Warnings, and the result's
+// PublishedFamily is the one that was published. With
+// UpdateFromInterfaces, whose missing families are never fatal, it adds
+// the same warnings.
+func BestEffort() UpdateOption {
+	return func(p *updateParams) error {
+		p.bestEffort = true
+		return nil
+	}
+}
+
+// DetectIPFamily is like ForceDetectIP, but asks the service over the
+// given address family, 4 or 6, to learn the address of that family.
+// Detected addresses are not cached.
+func (c *Client) DetectIPFamily(ctx context.Context, family int) (net.IP, error) {
+	network, err := familyNetwork(family)
+	if err != nil {
+		return nil, err
+	}
+	base, _ := http.DefaultTransport.(*http.Transport)
+	if c.transport != nil {
+		base = c.transport
+	}
+	tr := base.Clone()
+	tr.DisableKeepAlives = true
+	tr.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		if c.dial != nil {
+			return c.dial(ctx, &c.dialer, network, addr)
+		}
+		return c.dialer.DialContext(ctx, network, addr)
+	}
+	ip, err := c.queryCheckIP(ctx, &http.Client{Transport: tr})
+	if err == nil && ipFamily(ip) != family {
+		err = fmt.Errorf("dyndns: checkip returned %v over IPv%d", ip, family)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ip, nil
+}
+
+func familyNetwork(family int) (string, error) {
+	switch family {
+	case 4:
+		return "tcp4", nil
+	case 6:
+		return "tcp6", nil
+	}
+	return "", fmt.Errorf("dyndns: invalid address family %d", family)
+}
+
+// UpdateDual detects the host's IPv4 and IPv6 addresses with
+// DetectIPFamily and publishes both for hostname in one request with
+// UpdateIPs. If either family cannot be detected, it fails with a
+// FamilyError unless BestEffort is given. A server that confirms both
+// addresses fills in the result's IPv4 and IPv6.
+func (c *Client) UpdateDual(ctx context.Context, hostname string, opts ...UpdateOption) (UpdateResult, error) {
+	params, err := newUpdateParams(opts)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	var ips []net.IP
+	var missing []error
+	for _, family := range []int{4, 6} {
+		ip, err := c.DetectIPFamily(ctx, family)
+		if err != nil {
+			missing = append(missing, &FamilyError{family, err})
+			continue
+		}
+		ips = append(ips, ip)
+	}
+	switch {
+	case len(ips) == 0:
+		return UpdateResult{}, errors.Join(missing...)
+	case len(missing) > 0 && !params.bestEffort:
+		return UpdateResult{}, missing[0]
+	}
+	res, err := c.UpdateIPs(ctx, hostname, ips, opts...)
+	return noteMissing(res, ips, missing), err
+}
+
+// noteMissing adds the missing families to res after a best-effort update
+// of ips.
+func noteMissing(res UpdateResult, ips []net.IP, missing []error) UpdateResult {
+	if len(missing) == 0 {
+		return res
+	}
+	res.Warnings = append(res.Warnings, missing...)
+	res.PublishedFamily = ipFamily(ips[0])
+	return res
+}
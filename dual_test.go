@@ -0,0 +1,90 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newDualClient returns a Client whose checkip requests over tcp4 and tcp6
+// reach test servers replying with v4 and v6. If v6 is empty, IPv6
+// connections fail.
+func newDualClient(t *testing.T, q *url.Values, v4, v6 string) *Client {
+	serve := func(ip string) *httptest.Server {
+		return newTestServer(t, "Current IP Address: "+ip, nil, nil)
+	}
+	srv4 := serve(v4)
+	var addr6 string
+	if v6 != "" {
+		addr6 = serve(v6).Listener.Addr().String()
+	}
+	c := &Client{URL: newTestServer(t, "good 1.2.3.4", nil, q).URL}
+	c.CheckIPURL = srv4.URL
+	c.dial = func(ctx context.Context, d *net.Dialer, network, addr string) (net.Conn, error) {
+		if network == "tcp6" {
+			if addr6 == "" {
+				return nil, errors.New("network is unreachable")
+			}
+			addr = addr6
+		}
+		return d.DialContext(ctx, "tcp", addr)
+	}
+	return c
+}
+
+func TestUpdateDual(t *testing.T) {
+	var q url.Values
+	c := newDualClient(t, &q, "1.2.3.4", "2001:db8::5")
+	res, err := c.UpdateDual(context.Background(), hostname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := q.Get("myip"); got != "1.2.3.4,2001:db8::5" {
+		t.Errorf("myip = %q", got)
+	}
+	if res.PublishedFamily != 0 || res.Family != 0 || len(res.Warnings) != 0 {
+		t.Errorf("PublishedFamily %d, Family %d, warnings %v", res.PublishedFamily, res.Family, res.Warnings)
+	}
+}
+
+func TestUpdateDualMissingFamily(t *testing.T) {
+	var q url.Values
+	c := newDualClient(t, &q, "1.2.3.4", "")
+	ctx := context.Background()
+	_, err := c.UpdateDual(ctx, hostname)
+	var fe *FamilyError
+	if !errors.As(err, &fe) || fe.Family != 6 {
+		t.Fatalf("err = %v, want IPv6 FamilyError", err)
+	}
+	if q != nil {
+		t.Error("request sent with a missing family")
+	}
+
+	res, err := c.UpdateDual(ctx, hostname, BestEffort())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := q.Get("myip"); got != "1.2.3.4" {
+		t.Errorf("myip = %q", got)
+	}
+	if res.PublishedFamily != 4 || res.Family != 0 || len(res.Warnings) != 1 || !errors.As(res.Warnings[0], &fe) || fe.Family != 6 {
+		t.Errorf("PublishedFamily %d, Family %d, warnings %v", res.PublishedFamily, res.Family, res.Warnings)
+	}
+}
+
+func TestUpdateFromInterfacesBestEffort(t *testing.T) {
+	var q url.Values
+	c := &Client{URL: newTestServer(t, "good 1.2.3.4", nil, &q).URL}
+	c.interfaceAddrs = stubInterfaces(map[string][]string{"wan0": {"1.2.3.4/24"}})
+	res, err := c.UpdateFromInterfaces(context.Background(), hostname, []string{"wan0"}, BestEffort())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.PublishedFamily != 4 || res.Family != 0 || len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0].Error(), "IPv6") {
+		t.Errorf("PublishedFamily %d, Family %d, warnings %v", res.PublishedFamily, res.Family, res.Warnings)
+	}
+}
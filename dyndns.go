@@ -5,10 +5,9 @@
 package dyndns
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"net"
-	"net/http"
-	"strings"
 )
 
 // UserAgent identifies the client in update requests.
@@ -22,45 +21,35 @@ type Service struct {
 // Update sends a request to the service to change the hostname to ip.
 // If ip is nil, the update server will use the client's IP address.
 // It returns the updated IP address on success and an error, if any.
+// Unlike Client.Update, an unchanged hostname is reported as NoChange.
 func (s Service) Update(hostname string, ip net.IP) (net.IP, error) {
-
-	// Prepare HTTP request.
-	url := s.URL + "?hostname=" + hostname
-	if ip != nil {
-		url += "&myip=" + ip.String()
-		ip = nil // ip is reused for output.
+	c := Client{URL: s.URL, Credentials: StaticCredentials(s.Username, s.Password)}
+	res, err := c.Update(context.Background(), hostname, ip)
+	if err == nil && res.Code == CodeNoChange {
+		err = NoChange
 	}
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+	if re, ok := err.(*ResponseError); ok {
+		err = re.Err
 	}
-	req.SetBasicAuth(s.Username, s.Password)
-	req.Header.Add("User-Agent", UserAgent)
+	return res.IP, err
+}
 
-	// Execute the request.
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	// Parse the response.
-	buf := bufio.NewReader(resp.Body)
-	code, _ := buf.ReadString(' ')
-	code = strings.TrimSpace(code)
-	info, _ := buf.ReadString(0)
-	if code == "good" || code == NoChange.Code {
-		ip = net.ParseIP(info)
-	}
-	err = errors[code]
-	if err == nil && code != "good" {
-		err = &Error{"invalid response code", code}
-	}
-	return ip, err
+// IsNoChange reports whether err is the NoChange sentinel returned by
+// Service.Update for an unchanged hostname. Client.Update reports nochg as
+// a success instead; use WasNoChange on its result.
+func IsNoChange(err error) bool {
+	return errors.Is(err, NoChange)
+}
+
+// WasNoChange reports whether res is a nochg response. Together with
+// IsNoChange it lets callers move from Service.Update to Client.Update
+// incrementally.
+func WasNoChange(res UpdateResult) bool {
+	return res.Code == CodeNoChange
 }
 
-// errors maps return code text to an error.
-var errors = make(map[string]error)
+// codeErrors maps return code text to an error.
+var codeErrors = make(map[string]error)
 
 // Update protocol errors.
 type Error struct {
@@ -70,7 +59,7 @@ type Error struct {
 // NewError returns a new Error from a return code and description.
 func NewError(code, description string) *Error {
 	err := &Error{code, description}
-	errors[code] = err
+	codeErrors[code] = err
 	return err
 }
 
@@ -83,6 +72,24 @@ func (e *Error) Error() string {
 	return str
 }
 
+// A ResponseError is a protocol error carrying the extra text a server
+// sent after the return code in a specific response. It unwraps to the
+// registered error for the code, so errors.Is(err, ErrAbuse) still holds.
+type ResponseError struct {
+	Err    error
+	Detail string
+}
+
+// Error satisfies the built-in error interface.
+func (e *ResponseError) Error() string {
+	return e.Err.Error() + " (" + e.Detail + ")"
+}
+
+// Unwrap returns the registered error for the return code.
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
+
 // Update protocol response codes.
 //
 // http://dyn.com/support/developers/api/return-codes/
@@ -99,10 +106,62 @@ var (
 	ErrNumHost = NewError("numhost", "too many hosts")
 	ErrAbuse   = NewError("abuse", "hostname blocked for update abuse")
 
-	// User agent errors.
+	// Legacy hostname errors returned by some compatible services.
+	ErrNotYours  = NewError("!yours", "hostname belongs to another account")
+	ErrNotActive = NewError("!active", "hostname is not active")
+
+	// User agent errors. Servers often add the cause, such as
+	// "badagent no user agent"; Client.Update returns it in a ResponseError.
 	ErrAgent = NewError("badagent", "bad user agent or http method")
 
+	// Request errors.
+	ErrSystem = NewError("badsys", "invalid system parameter")
+
 	// Server errors.
 	ErrDns = NewError("dnserror", "dns error")
 	Err911 = NewError("911", "server problem or scheduled maintenance")
 )
+
+// A Code is an update protocol return code.
+type Code string
+
+// Return codes defined by the update protocol.
+const (
+	CodeGood       Code = "good"
+	CodeNoChange   Code = "nochg"
+	CodeBadAuth    Code = "badauth"
+	CodeNotDonator Code = "!donator"
+	CodeNotFQDN    Code = "notfqdn"
+	CodeNoHost     Code = "nohost"
+	CodeNumHost    Code = "numhost"
+	CodeAbuse      Code = "abuse"
+	CodeNotYours   Code = "!yours"
+	CodeNotActive  Code = "!active"
+	CodeBadAgent   Code = "badagent"
+	CodeBadSys     Code = "badsys"
+	CodeDNSError   Code = "dnserror"
+	Code911        Code = "911"
+)
+
+// IsSuccess reports whether c indicates a successful update request.
+// Both good and nochg are successes.
+func (c Code) IsSuccess() bool {
+	return c == CodeGood || c == CodeNoChange
+}
+
+// known reports whether c is a success code or has a registered error.
+func (c Code) known() bool {
+	_, ok := codeErrors[string(c)]
+	return ok || c.IsSuccess()
+}
+
+// Err returns the error registered for c, or nil if c is a success code.
+func (c Code) Err() error {
+	if c.IsSuccess() {
+		return nil
+	}
+	if err, ok := codeErrors[string(c)]; ok {
+		return err
+	}
+	return &Error{"invalid response code", string(c)}
+}
@@ -1,6 +1,8 @@
 package dyndns
 
 import (
+	"context"
+	"net"
 	"testing"
 )
 
@@ -46,3 +48,23 @@ func TestNoHost(t *testing.T) {
 	}
 	t.Log(ip)
 }
+
+func TestNoChangeHelpers(t *testing.T) {
+	c := newTestClient(t, "nochg 1.2.3.4")
+
+	// Legacy path: NoChange is an error.
+	ip, err := Service{c.URL, username, password}.Update(hostname, nil)
+	if !IsNoChange(err) || err != NoChange || !ip.Equal(net.IPv4(1, 2, 3, 4)) {
+		t.Errorf("Service.Update = %v, %v", ip, err)
+	}
+
+	// New path: nochg is a successful result.
+	res, err := c.Update(context.Background(), hostname, nil)
+	if err != nil || IsNoChange(err) || !WasNoChange(res) {
+		t.Errorf("Client.Update = %+v, %v", res, err)
+	}
+
+	if IsNoChange(ErrAbuse) || IsNoChange(nil) || WasNoChange(UpdateResult{Code: CodeGood}) {
+		t.Error("helpers matched other outcomes")
+	}
+}
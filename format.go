@@ -0,0 +1,54 @@
+package dyndns
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+// A ResponseFormat is the encoding of a server's update response.
+type ResponseFormat int
+
+const (
+	// PlainText is the standard protocol response: a return code,
+	// optionally followed by a space and the address, one line per hostname.
+	PlainText ResponseFormat = iota
+
+	// JSON is an object such as {"status":"good","ip":"1.2.3.4"}, as sent
+	// by some newer compatible APIs. The status is a protocol return code
+	// and an optional "message" is kept as the result's Info. For
+	// UpdateMany, each line holds one object.
+	JSON
+)
+
+// utf8BOM is the byte order mark some servers put before a response.
+var utf8BOM = []byte("\ufeff")
+
+// parse parses the response body for one hostname. A leading byte order
+// mark and whitespace are ignored.
+func (c *Client) parse(body []byte) UpdateResult {
+	body = bytes.TrimLeftFunc(bytes.TrimPrefix(body, utf8BOM), unicode.IsSpace)
+	if c.format == JSON {
+		return parseJSONResponse(body, c.isSuccess)
+	}
+	return parseResponse(bytes.NewReader(body), c.isSuccess)
+}
+
+// parseJSONResponse parses a JSON update response. A malformed body yields
+// a result with an empty code, which Err reports as invalid.
+func parseJSONResponse(body []byte, success func(Code) bool) UpdateResult {
+	var v struct {
+		Status  string `json:"status"`
+		IP      string `json:"ip"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return UpdateResult{Info: strings.TrimSpace(string(body))}
+	}
+	res := UpdateResult{Code: Code(v.Status), Info: v.Message}
+	if success(res.Code) {
+		res.setIPs(v.IP)
+	}
+	return res
+}
@@ -0,0 +1,63 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestJSONResponse(t *testing.T) {
+	for _, tt := range []struct {
+		body string
+		code Code
+		ip   net.IP
+		err  error
+	}{
+		{`{"status":"good","ip":"1.2.3.4"}`, CodeGood, net.IPv4(1, 2, 3, 4), nil},
+		{`{"status":"nochg","ip":"2001:db8::1"}` + "\n", CodeNoChange, net.ParseIP("2001:db8::1"), nil},
+		{`{"status":"badauth"}`, CodeBadAuth, nil, ErrAuth},
+		{`{"status":"abuse","message":"slow down"}`, CodeAbuse, nil, ErrAbuse},
+		{"\ufeff" + `{"status":"good","ip":"1.2.3.4"}`, CodeGood, net.IPv4(1, 2, 3, 4), nil},
+	} {
+		c := newTestClient(t, tt.body)
+		c.format = JSON
+		res, err := c.Update(context.Background(), hostname, nil)
+		if res.Code != tt.code || !res.IP.Equal(tt.ip) || !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v %v, %v; want %v %v, %v", tt.body, res.Code, res.IP, err, tt.code, tt.ip, tt.err)
+		}
+	}
+}
+
+func TestJSONResponseMalformed(t *testing.T) {
+	c := newTestClient(t, "good 1.2.3.4")
+	c.format = JSON
+	if res, err := c.Update(context.Background(), hostname, nil); err == nil || res.Code.IsSuccess() {
+		t.Errorf("plain text parsed as JSON: %+v, %v", res, err)
+	}
+}
+
+func TestJSONResponseMany(t *testing.T) {
+	c := newTestClient(t, `{"status":"good","ip":"1.2.3.4"}`+"\n"+`{"status":"nohost"}`+"\n")
+	c.format = JSON
+	results, err := c.UpdateMany(context.Background(), []string{"a.dyndns.org", "b.dyndns.org"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Code != CodeGood || results[1].Err != ErrNoHost {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestLeadingBOMAndWhitespace(t *testing.T) {
+	for _, body := range []string{
+		"\ufeffgood 1.2.3.4",
+		"\ufeff\r\n good 1.2.3.4\n",
+		"\n\tgood 1.2.3.4",
+	} {
+		res, err := newTestClient(t, body).Update(context.Background(), hostname, nil)
+		if err != nil || res.Code != CodeGood || !res.IP.Equal(net.IPv4(1, 2, 3, 4)) {
+			t.Errorf("%q: got %q %v, %v", body, res.Code, res.IP, err)
+		}
+	}
+}
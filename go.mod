@@ -0,0 +1,7 @@
+module github.com/vidanio/go-dyndns
+
+go 1.23.0
+
+require golang.org/x/net v0.38.0
+
+require golang.org/x/text v0.23.0 // indirect
@@ -0,0 +1,124 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrGroupInconsistent matches a GroupError with errors.Is when some
+// hostnames of the group failed with a non-transient error, so retrying
+// the group is unlikely to make it consistent.
+var ErrGroupInconsistent = errors.New("dyndns: hostname group is inconsistent")
+
+// A GroupError is returned by UpdateGroup when some hostnames failed.
+// The service may still point the Updated hostnames to the new address.
+type GroupError struct {
+	Updated []string     // hostnames the service accepted
+	Failed  []*HostError // hostnames that failed, with their errors
+}
+
+// Error satisfies the built-in error interface.
+func (e *GroupError) Error() string {
+	failed := make([]string, len(e.Failed))
+	for i, f := range e.Failed {
+		failed[i] = f.Error()
+	}
+	return fmt.Sprintf("dyndns: group update failed: updated [%s], failed [%s]",
+		strings.Join(e.Updated, ", "), strings.Join(failed, "; "))
+}
+
+// Is reports whether target is ErrGroupInconsistent and some failure is
+// not transient.
+func (e *GroupError) Is(target error) bool {
+	if target != ErrGroupInconsistent {
+		return false
+	}
+	for _, f := range e.Failed {
+		if !IsTransient(f.Err) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap returns the per-hostname errors.
+func (e *GroupError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, f := range e.Failed {
+		errs[i] = f
+	}
+	return errs
+}
+
+// UpdateGroup updates hostnames that must point to the same address
+// together, one request per hostname. The protocol has no transactions,
+// so the group is all-or-nothing only in the Client's state: if any
+// hostname fails, the Client forgets the updates of the others, as if they
+// had not been sent, and UpdateGroup returns a GroupError. Updating the
+// group again then resends every hostname. Likewise, OnChange is called
+// for the group's changes only once every hostname has been updated.
+func (c *Client) UpdateGroup(ctx context.Context, hostnames []string, ip net.IP, opts ...UpdateOption) ([]Result, error) {
+	saved := c.saveHosts(hostnames)
+	var changes []hostChange
+	opts = append(opts[:len(opts):len(opts)], func(p *updateParams) error {
+		p.changes = &changes
+		return nil
+	})
+	results := make([]Result, len(hostnames))
+	ge := new(GroupError)
+	for i, name := range hostnames {
+		r := &results[i]
+		r.Hostname = name
+		r.UpdateResult, r.Err = c.Update(ctx, name, ip, opts...)
+		if r.Err != nil {
+			ge.Failed = append(ge.Failed, &HostError{name, r.Err})
+		} else {
+			ge.Updated = append(ge.Updated, name)
+		}
+	}
+	if len(ge.Failed) == 0 {
+		if c.OnChange != nil {
+			for _, ch := range changes {
+				c.OnChange(ch.hostname, ch.old, ch.new)
+			}
+		}
+		return results, nil
+	}
+	c.restoreHosts(saved, ge.Updated)
+	return results, ge
+}
+
+// saveHosts returns a copy of the state of hostnames, nil for hostnames
+// without state.
+func (c *Client) saveHosts(hostnames []string) map[string]*hostState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	saved := make(map[string]*hostState)
+	for _, name := range hostnames {
+		name = c.stateKey(name)
+		if h := c.hosts[name]; h != nil {
+			s := *h
+			saved[name] = &s
+		} else {
+			saved[name] = nil
+		}
+	}
+	return saved
+}
+
+// restoreHosts resets the state of hostnames to that saved by saveHosts.
+func (c *Client) restoreHosts(saved map[string]*hostState, hostnames []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, name := range hostnames {
+		name = c.stateKey(name)
+		if h := saved[name]; h != nil {
+			c.hosts[name] = h
+		} else {
+			delete(c.hosts, name)
+		}
+	}
+}
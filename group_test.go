@@ -0,0 +1,90 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// newGroupServer returns a Client whose test server replies to each
+// hostname with the body in replies, or good 1.2.3.4.
+func newGroupServer(t *testing.T, replies map[string]string) *Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := replies[r.URL.Query().Get("hostname")]
+		if !ok {
+			body = "good 1.2.3.4"
+		}
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return &Client{URL: srv.URL}
+}
+
+func TestUpdateGroup(t *testing.T) {
+	c := newGroupServer(t, nil)
+	var changed []string
+	c.OnChange = func(hostname string, _, _ net.IP) { changed = append(changed, hostname) }
+	hosts := []string{"a.dyndns.org", "b.dyndns.org"}
+	results, err := c.UpdateGroup(context.Background(), hosts, net.IPv4(1, 2, 3, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changed, hosts) {
+		t.Errorf("OnChange called for %q, want %q", changed, hosts)
+	}
+	for i, r := range results {
+		if r.Hostname != hosts[i] || r.Err != nil || r.Code != CodeGood {
+			t.Errorf("result %d = %+v", i, r)
+		}
+		if _, ip := c.lastUpdate(hosts[i]); !ip.Equal(net.IPv4(1, 2, 3, 4)) {
+			t.Errorf("%s: recorded %v", hosts[i], ip)
+		}
+	}
+}
+
+func TestUpdateGroupPartialFailure(t *testing.T) {
+	c := newGroupServer(t, map[string]string{"c.dyndns.org": "nohost"})
+	ctx := context.Background()
+	c.mu.Lock()
+	c.host("a.dyndns.org").ip = net.IPv4(5, 6, 7, 8)
+	c.mu.Unlock()
+	c.OnChange = func(hostname string, _, _ net.IP) {
+		t.Errorf("OnChange called for %s, whose update was rolled back", hostname)
+	}
+
+	hosts := []string{"a.dyndns.org", "b.dyndns.org", "c.dyndns.org"}
+	results, err := c.UpdateGroup(ctx, hosts, net.IPv4(1, 2, 3, 4))
+	if !errors.Is(err, ErrGroupInconsistent) || !errors.Is(err, ErrNoHost) {
+		t.Fatalf("err = %v, want ErrGroupInconsistent wrapping ErrNoHost", err)
+	}
+	var ge *GroupError
+	errors.As(err, &ge)
+	if !reflect.DeepEqual(ge.Updated, hosts[:2]) || len(ge.Failed) != 1 || ge.Failed[0].Hostname != "c.dyndns.org" {
+		t.Errorf("GroupError = %v", ge)
+	}
+	if len(results) != 3 || results[2].Err == nil {
+		t.Errorf("results = %+v", results)
+	}
+
+	// The successful updates are forgotten.
+	if _, ip := c.lastUpdate("a.dyndns.org"); !ip.Equal(net.IPv4(5, 6, 7, 8)) {
+		t.Errorf("a: recorded %v, want the address from before the group", ip)
+	}
+	if _, ip := c.lastUpdate("b.dyndns.org"); ip != nil {
+		t.Errorf("b: recorded %v, want none", ip)
+	}
+}
+
+func TestUpdateGroupTransientFailure(t *testing.T) {
+	c := newGroupServer(t, map[string]string{"b.dyndns.org": "911"})
+	_, err := c.UpdateGroup(context.Background(), []string{"a.dyndns.org", "b.dyndns.org"}, nil)
+	var ge *GroupError
+	if !errors.As(err, &ge) || errors.Is(err, ErrGroupInconsistent) {
+		t.Errorf("err = %v, want a GroupError that is not inconsistent", err)
+	}
+}
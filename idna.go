@@ -0,0 +1,36 @@
+package dyndns
+
+import (
+	"fmt"
+
+	"golang.org/x/net/idna"
+)
+
+// normalizeHostname returns the form of hostname sent to the service.
+func (c *Client) normalizeHostname(hostname string) (string, error) {
+	if c.noIDNA {
+		return hostname, nil
+	}
+	return toASCII(hostname)
+}
+
+// idnaProfile is idna.Lookup with DNS length checks: labels of at most 63
+// bytes and names of at most 253.
+var idnaProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.VerifyDNSLength(true),
+)
+
+// toASCII converts a hostname with Unicode labels to its ASCII form as for
+// a DNS lookup (UTS #46): labels are case-folded and normalized to NFC,
+// width variants are mapped to their usual form, and non-ASCII labels are
+// replaced by their Punycode encoding with the "xn--" prefix. Labels
+// already encoded pass through, so the conversion is idempotent.
+func toASCII(hostname string) (string, error) {
+	name, err := idnaProfile.ToASCII(hostname)
+	if err != nil {
+		return "", fmt.Errorf("dyndns: invalid hostname %q: %w", hostname, err)
+	}
+	return name, nil
+}
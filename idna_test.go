@@ -0,0 +1,64 @@
+package dyndns
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestToASCII(t *testing.T) {
+	for in, want := range map[string]string{
+		"test.dyndns.org":             "test.dyndns.org",
+		"Test.DynDNS.org":             "test.dyndns.org",
+		"bücher.example":              "xn--bcher-kva.example",
+		"BÜCHER.example":              "xn--bcher-kva.example",
+		"xn--bcher-kva.example":       "xn--bcher-kva.example",
+		"München.de":                  "xn--mnchen-3ya.de",
+		"пример.испытание":            "xn--e1afmkfd.xn--80akhbyknj4f",
+		"ドメイン名例.jp":                   "xn--eckwd4c7cu47r2wf.jp",
+		"home.xn--e1afmkfd.испытание": "home.xn--e1afmkfd.xn--80akhbyknj4f",
+		"bu\u0308cher.example":        "xn--bcher-kva.example", // NFD
+		"ＥＸＡＭＰＬＥ.com":                 "example.com",           // full width
+	} {
+		got, err := toASCII(in)
+		if err != nil {
+			t.Errorf("toASCII(%q): %v", in, err)
+		} else if got != want {
+			t.Errorf("toASCII(%q) = %q, want %q", in, got, want)
+		}
+		if again, _ := toASCII(got); again != got {
+			t.Errorf("toASCII(%q) = %q, not idempotent", got, again)
+		}
+	}
+}
+
+func TestToASCIIInvalid(t *testing.T) {
+	for _, in := range []string{
+		strings.Repeat("a", 64) + ".example",
+		"-home.example",
+		"a b.example",
+	} {
+		if got, err := toASCII(in); err == nil {
+			t.Errorf("toASCII(%q) = %q, want error", in, got)
+		}
+	}
+	if _, err := toASCII(strings.Repeat("a", 63) + ".example"); err != nil {
+		t.Errorf("63-byte label: %v", err)
+	}
+}
+
+func TestUpdateIDNA(t *testing.T) {
+	var q url.Values
+	c := &Client{URL: newTestServer(t, "good 1.2.3.4", nil, &q).URL}
+	c.Update(context.Background(), "bücher.example", nil)
+	if got := q.Get("hostname"); got != "xn--bcher-kva.example" {
+		t.Errorf("hostname = %q, want punycode", got)
+	}
+
+	c.noIDNA = true
+	c.Update(context.Background(), "bücher.example", nil)
+	if got := q.Get("hostname"); got != "bücher.example" {
+		t.Errorf("with IDNA disabled, hostname = %q", got)
+	}
+}
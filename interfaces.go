@@ -0,0 +1,100 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// UpdateFromInterfaces publishes the global addresses of the named network
+// interfaces as a round-robin set for hostname with UpdateIPs, as for a
+// router with several WAN links. Link-local, private and loopback
+// addresses are not used. Interfaces without a usable address are skipped;
+// it is an error only if none of them has one.
+func (c *Client) UpdateFromInterfaces(ctx context.Context, hostname string, ifaceNames []string, opts ...UpdateOption) (UpdateResult, error) {
+	params, err := newUpdateParams(opts)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	ips, err := c.interfaceIPs(ifaceNames)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	var missing []error
+	if params.bestEffort {
+		has := make(map[int]bool)
+		for _, ip := range ips {
+			has[ipFamily(ip)] = true
+		}
+		for _, family := range []int{4, 6} {
+			if !has[family] {
+				missing = append(missing, &FamilyError{family, errors.New("no global address on interfaces")})
+			}
+		}
+	}
+	res, err := c.UpdateIPs(ctx, hostname, ips, opts...)
+	return noteMissing(res, ips, missing), err
+}
+
+// interfaceIPs returns the distinct global addresses of the named interfaces.
+func (c *Client) interfaceIPs(names []string) ([]net.IP, error) {
+	lookup := c.interfaceAddrs
+	if lookup == nil {
+		lookup = interfaceAddrs
+	}
+	return globalAddrs(names, lookup)
+}
+
+// globalAddrs returns the distinct global addresses of the named
+// interfaces, whose addresses are returned by lookup.
+func globalAddrs(names []string, lookup func(string) ([]net.Addr, error)) ([]net.IP, error) {
+	var ips []net.IP
+	var errs []error
+	for _, name := range names {
+		addrs, err := lookup(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+	next:
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok || !isGlobal(ipnet.IP) {
+				continue
+			}
+			for _, ip := range ips {
+				if ip.Equal(ipnet.IP) {
+					continue next
+				}
+			}
+			ips = append(ips, ipnet.IP)
+		}
+	}
+	if len(ips) == 0 {
+		err := fmt.Errorf("dyndns: no global address on interfaces %q", names)
+		return nil, errors.Join(append([]error{err}, errs...)...)
+	}
+	return ips, nil
+}
+
+// interfaceAddrs returns the addresses of the named interface.
+func interfaceAddrs(name string) ([]net.Addr, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return iface.Addrs()
+}
+
+func ipFamily(ip net.IP) int {
+	if ip.To4() != nil {
+		return 4
+	}
+	return 6
+}
+
+// isGlobal reports whether ip can be published as a public address.
+func isGlobal(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate()
+}
@@ -0,0 +1,53 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func stubInterfaces(ifaces map[string][]string) func(string) ([]net.Addr, error) {
+	return func(name string) ([]net.Addr, error) {
+		cidrs, ok := ifaces[name]
+		if !ok {
+			return nil, errors.New("no such interface " + name)
+		}
+		var addrs []net.Addr
+		for _, s := range cidrs {
+			ip, ipnet, err := net.ParseCIDR(s)
+			if err != nil {
+				panic(err)
+			}
+			ipnet.IP = ip
+			addrs = append(addrs, ipnet)
+		}
+		return addrs, nil
+	}
+}
+
+func TestUpdateFromInterfaces(t *testing.T) {
+	var q url.Values
+	c := &Client{URL: newTestServer(t, "good 1.2.3.4", nil, &q).URL}
+	c.interfaceAddrs = stubInterfaces(map[string][]string{
+		"wan0": {"203.0.113.5/24", "fe80::1/64", "2001:db8::5/64"},
+		"wan1": {"198.51.100.7/24", "203.0.113.5/24"},
+		"lan0": {"192.168.1.1/24", "fd00::1/64"},
+	})
+	ctx := context.Background()
+	if _, err := c.UpdateFromInterfaces(ctx, hostname, []string{"wan0", "lan0", "missing", "wan1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Get("myip"), "203.0.113.5,2001:db8::5,198.51.100.7"; got != want {
+		t.Errorf("myip = %q, want %q", got, want)
+	}
+
+	q = nil
+	if _, err := c.UpdateFromInterfaces(ctx, hostname, []string{"lan0", "missing"}); err == nil {
+		t.Error("no error without global addresses")
+	}
+	if q != nil {
+		t.Error("request sent without global addresses")
+	}
+}
@@ -0,0 +1,382 @@
+package dyndns
+
+import (
+	"container/heap"
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// A Monitor keeps hostnames up to date. It updates each hostname when
+// started, every interval, and whenever Trigger is called. Each hostname
+// has its own schedule, so hostnames on providers with different
+// politeness expectations can share a Monitor. Updates go through the
+// same Client, reusing its idle connections between ticks.
+type Monitor struct {
+	Client *Client
+
+	// Hostname is a shorthand for a single entry in Hosts.
+	// It is ignored if Hosts is not empty.
+	Hostname string
+
+	// Hosts lists the hostnames to keep up to date. Use SetHosts to
+	// change it while the Monitor runs.
+	Hosts []HostConfig
+
+	// IP returns the address to publish. If nil, the service uses the
+	// address the request comes from.
+	IP func(ctx context.Context) (net.IP, error)
+
+	// Source, if IP is nil, returns the address to publish with
+	// CurrentIP(ctx, 0).
+	Source IPSource
+
+	// Interval is the default time between periodic updates of a hostname.
+	// Periodic updates respect the Client's NextSafeUpdate unless the
+	// address has changed. If zero, hostnames without their own interval
+	// are updated only on start and on Trigger.
+	Interval time.Duration
+
+	// TriggerDebounce coalesces calls to Trigger. The first call starts
+	// a window of this length, and a single update is sent when it ends
+	// no matter how many more calls arrive. If zero, every call updates.
+	TriggerDebounce time.Duration
+
+	// OnUpdate, if non-nil, is called after each update attempt.
+	OnUpdate func(Result)
+
+	once    sync.Once
+	trigger chan struct{}
+	reload  chan struct{}
+	mu      sync.Mutex // guards Hostname, Hosts and events
+
+	events chan Event // for the current or next Run
+}
+
+// An Event is an update attempt reported on a Monitor's Events channel.
+type Event struct {
+	Result
+	Time time.Time // when the attempt finished
+}
+
+// EventBuffer is the capacity of a Monitor's Events channel.
+const EventBuffer = 64
+
+// A HostConfig schedules the updates of one hostname in a Monitor.
+type HostConfig struct {
+	Hostname string
+
+	// Client sends the hostname's updates. If nil, Monitor.Client is used.
+	Client *Client
+
+	// Interval is the time between periodic updates.
+	// If zero, Monitor.Interval is used.
+	Interval time.Duration
+
+	// MinSpacing is the minimum time between any two updates of the
+	// hostname, including triggered ones. Updates that would come sooner
+	// are postponed.
+	MinSpacing time.Duration
+}
+
+// hostEntry is a hostname scheduled by a running Monitor.
+type hostEntry struct {
+	HostConfig
+	due     time.Time // next update, or zero if none is scheduled
+	last    time.Time // last update attempt
+	pending bool      // whether the next update is a postponed trigger
+	index   int       // position in the schedule, or -1
+}
+
+// schedule is a priority queue of hostEntries ordered by due time.
+type schedule []*hostEntry
+
+func (s schedule) Len() int           { return len(s) }
+func (s schedule) Less(i, j int) bool { return s[i].due.Before(s[j].due) }
+func (s schedule) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+	s[i].index, s[j].index = i, j
+}
+
+func (s *schedule) Push(x any) {
+	e := x.(*hostEntry)
+	e.index = len(*s)
+	*s = append(*s, e)
+}
+
+func (s *schedule) Pop() any {
+	old := *s
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	e.index = -1
+	*s = old[:len(old)-1]
+	return e
+}
+
+// reschedule sets the due time of e, adding it to s or removing it as
+// needed. A zero due time unschedules e.
+func (s *schedule) reschedule(e *hostEntry, due time.Time) {
+	e.due = due
+	switch {
+	case e.index >= 0 && due.IsZero():
+		heap.Remove(s, e.index)
+	case e.index >= 0:
+		heap.Fix(s, e.index)
+	case !due.IsZero():
+		heap.Push(s, e)
+	}
+}
+
+func (m *Monitor) init() {
+	m.once.Do(func() {
+		m.trigger = make(chan struct{}, 1)
+		m.reload = make(chan struct{}, 1)
+	})
+}
+
+// Trigger requests an update of every hostname outside the regular
+// schedule, for example after a network link change. It does not block.
+func (m *Monitor) Trigger() {
+	m.init()
+	select {
+	case m.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Events returns a channel receiving an Event for each update attempt, as
+// an alternative to OnUpdate. The channel buffers EventBuffer events; when
+// it is full, new events are dropped rather than delaying updates, so
+// consumers should keep up.
+//
+// The channel is closed when Run returns. A later Run sends its events to
+// a new channel, so call Events again before each Run.
+func (m *Monitor) Events() <-chan Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.events == nil {
+		m.events = make(chan Event, EventBuffer)
+	}
+	return m.events
+}
+
+// closeEvents closes the events channel of a Run that is returning, so
+// that the next Run gets a new one.
+func (m *Monitor) closeEvents() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.events != nil {
+		close(m.events)
+		m.events = nil
+	}
+}
+
+// SetHosts replaces the hostnames to keep up to date. It is safe to call
+// while the Monitor runs: new hostnames are updated right away, removed
+// ones are no longer updated, and hostnames that stay keep their last
+// update time but follow their new configuration.
+func (m *Monitor) SetHosts(hosts []HostConfig) {
+	m.init()
+	m.mu.Lock()
+	m.Hostname, m.Hosts = "", append([]HostConfig(nil), hosts...)
+	m.mu.Unlock()
+	select {
+	case m.reload <- struct{}{}:
+	default:
+	}
+}
+
+// hostConfigs returns the configured hostnames.
+func (m *Monitor) hostConfigs() []HostConfig {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.Hosts) == 0 && m.Hostname != "" {
+		return []HostConfig{{Hostname: m.Hostname}}
+	}
+	return append([]HostConfig(nil), m.Hosts...)
+}
+
+// Run sends updates until ctx is done, then returns ctx.Err().
+func (m *Monitor) Run(ctx context.Context) error {
+	m.init()
+	defer m.closeEvents()
+	r := &monitorRun{m: m}
+	r.setHosts(m.hostConfigs())
+	var debounce <-chan time.Time
+	for {
+		var wake <-chan time.Time
+		var timer *time.Timer
+		if len(r.queue) > 0 {
+			timer = time.NewTimer(time.Until(r.queue[0].due))
+			wake = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+			r.runDue(ctx)
+		case <-m.trigger:
+			if m.TriggerDebounce <= 0 {
+				r.triggerAll(ctx)
+			} else if debounce == nil {
+				debounce = time.After(m.TriggerDebounce)
+			}
+		case <-debounce:
+			debounce = nil
+			r.triggerAll(ctx)
+		case <-m.reload:
+			r.setHosts(m.hostConfigs())
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// monitorRun is the state of a running Monitor.
+type monitorRun struct {
+	m     *Monitor
+	hosts []*hostEntry
+	queue schedule
+}
+
+// setHosts schedules the hostnames in configs, keeping the state of
+// hostnames already scheduled and dropping those not in configs.
+func (r *monitorRun) setHosts(configs []HostConfig) {
+	old := make(map[string]*hostEntry, len(r.hosts))
+	for _, e := range r.hosts {
+		old[e.Hostname] = e
+	}
+	now := time.Now()
+	r.hosts = r.hosts[:0]
+	for _, hc := range configs {
+		e := old[hc.Hostname]
+		if e == nil {
+			e = &hostEntry{HostConfig: hc, index: -1, pending: true}
+			r.queue.reschedule(e, now)
+		} else {
+			delete(old, hc.Hostname)
+			e.HostConfig = hc
+			if !e.pending {
+				r.queue.reschedule(e, r.nextDue(e))
+			}
+		}
+		r.hosts = append(r.hosts, e)
+	}
+	for _, e := range old {
+		r.queue.reschedule(e, time.Time{})
+	}
+}
+
+// runDue updates the hostnames whose time has come.
+func (r *monitorRun) runDue(ctx context.Context) {
+	for len(r.queue) > 0 && !r.queue[0].due.After(time.Now()) {
+		e := r.queue[0]
+		if e.pending {
+			r.m.update(ctx, e)
+		} else {
+			r.m.tick(ctx, e)
+		}
+		r.queue.reschedule(e, r.nextDue(e))
+	}
+}
+
+// triggerAll updates every hostname now, or as soon as its MinSpacing allows.
+func (r *monitorRun) triggerAll(ctx context.Context) {
+	for _, e := range r.hosts {
+		if next := e.last.Add(e.MinSpacing); time.Now().Before(next) {
+			e.pending = true
+			if e.due.IsZero() || next.Before(e.due) {
+				r.queue.reschedule(e, next)
+			}
+			continue
+		}
+		r.m.update(ctx, e)
+		r.queue.reschedule(e, r.nextDue(e))
+	}
+}
+
+// nextDue returns when e should next be updated periodically,
+// or zero if it has no interval.
+func (r *monitorRun) nextDue(e *hostEntry) time.Time {
+	interval := e.Interval
+	if interval <= 0 {
+		interval = r.m.Interval
+	}
+	if interval <= 0 {
+		return time.Time{}
+	}
+	if e.MinSpacing > interval {
+		interval = e.MinSpacing
+	}
+	return e.last.Add(interval)
+}
+
+func (e *hostEntry) client(m *Monitor) *Client {
+	if e.Client != nil {
+		return e.Client
+	}
+	return m.Client
+}
+
+// tick sends a periodic update. Unless the address to publish has changed,
+// the update is skipped while the Client's NextSafeUpdate is in the future.
+func (m *Monitor) tick(ctx context.Context, e *hostEntry) {
+	c := e.client(m)
+	if time.Now().After(c.NextSafeUpdate(e.Hostname)) {
+		m.update(ctx, e)
+		return
+	}
+	e.last = time.Now()
+	current := m.currentIP()
+	if current == nil {
+		return
+	}
+	ip, err := current(ctx)
+	if _, last := c.lastUpdate(e.Hostname); err != nil || !ip.Equal(last) {
+		m.send(ctx, e, ip, err)
+	}
+}
+
+func (m *Monitor) update(ctx context.Context, e *hostEntry) {
+	var ip net.IP
+	var err error
+	if current := m.currentIP(); current != nil {
+		ip, err = current(ctx)
+	}
+	m.send(ctx, e, ip, err)
+}
+
+// currentIP returns the function giving the address to publish,
+// or nil to let the service detect it.
+func (m *Monitor) currentIP() func(ctx context.Context) (net.IP, error) {
+	switch {
+	case m.IP != nil:
+		return m.IP
+	case m.Source != nil:
+		return func(ctx context.Context) (net.IP, error) {
+			return m.Source.CurrentIP(ctx, 0)
+		}
+	}
+	return nil
+}
+
+// send updates the hostname to ip unless getting ip failed with err.
+func (m *Monitor) send(ctx context.Context, e *hostEntry, ip net.IP, err error) {
+	e.last, e.pending = time.Now(), false
+	r := Result{Hostname: e.Hostname, Err: err}
+	if err == nil {
+		r.UpdateResult, r.Err = e.client(m).Update(ctx, e.Hostname, ip)
+	}
+	if m.OnUpdate != nil {
+		m.OnUpdate(r)
+	}
+	m.mu.Lock()
+	events := m.events
+	m.mu.Unlock()
+	select {
+	case events <- Event{r, time.Now()}: // nil if Events was not called
+	default:
+	}
+}
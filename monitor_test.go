@@ -0,0 +1,273 @@
+package dyndns
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMonitorTriggerDebounce(t *testing.T) {
+	updates := make(chan error, 20)
+	m := &Monitor{
+		Client:          newTestClient(t, "good 1.2.3.4"),
+		Hostname:        hostname,
+		TriggerDebounce: 50 * time.Millisecond,
+		OnUpdate:        func(r Result) { updates <- r.Err },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+
+	if err := <-updates; err != nil { // initial update
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		m.Trigger()
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-updates:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no update after triggers")
+	}
+	select {
+	case <-updates:
+		t.Error("triggers were not coalesced into a single update")
+	case <-time.After(150 * time.Millisecond):
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run returned %v", err)
+	}
+}
+
+func TestMonitorReusesConnection(t *testing.T) {
+	srv := newTestServer(t, "good 1.2.3.4", nil, nil)
+	c, err := NewClient(srv.URL, AllowInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dials int32
+	c.dial = func(ctx context.Context, d *net.Dialer, network, addr string) (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		return d.DialContext(ctx, network, addr)
+	}
+	updates := make(chan error, 2)
+	m := &Monitor{
+		Client:   c,
+		Hostname: hostname,
+		OnUpdate: func(r Result) { updates <- r.Err },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+	for i := 0; i < 2; i++ {
+		if err := <-updates; err != nil {
+			t.Fatal(err)
+		}
+		m.Trigger()
+	}
+	if n := atomic.LoadInt32(&dials); n != 1 {
+		t.Errorf("dialed %d connections for two updates, want 1", n)
+	}
+}
+
+func TestMonitorRespectsNextSafeUpdate(t *testing.T) {
+	testMonitorRespectsNextSafeUpdate(t, hostname)
+}
+
+func TestMonitorRespectsNextSafeUpdateMixedCase(t *testing.T) {
+	testMonitorRespectsNextSafeUpdate(t, "Test.DynDNS.org")
+}
+
+func testMonitorRespectsNextSafeUpdate(t *testing.T, hostname string) {
+	var n int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		io.WriteString(w, "nochg 1.2.3.4")
+	}))
+	defer srv.Close()
+	var current atomic.Value
+	current.Store(net.IPv4(1, 2, 3, 4))
+	m := &Monitor{
+		Client:   &Client{URL: srv.URL, MinUpdateInterval: time.Hour},
+		Hostname: hostname,
+		IP:       func(context.Context) (net.IP, error) { return current.Load().(net.IP), nil },
+		Interval: 10 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+	time.Sleep(100 * time.Millisecond)
+	if got := atomic.LoadInt32(&n); got != 1 {
+		t.Errorf("with unchanged address, %d updates sent, want 1", got)
+	}
+	current.Store(net.IPv4(5, 6, 7, 8))
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt32(&n); got < 2 {
+		t.Error("changed address was not published before NextSafeUpdate")
+	}
+}
+
+func TestMonitorPerHostIntervals(t *testing.T) {
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	m := &Monitor{
+		Client: newTestClient(t, "good 1.2.3.4"),
+		Hosts: []HostConfig{
+			{Hostname: "fast.example.org", Interval: 20 * time.Millisecond},
+			{Hostname: "slow.example.org", Interval: 100 * time.Millisecond},
+		},
+		OnUpdate: func(r Result) {
+			mu.Lock()
+			counts[r.Hostname]++
+			mu.Unlock()
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	m.Run(ctx)
+	mu.Lock()
+	defer mu.Unlock()
+	fast, slow := counts["fast.example.org"], counts["slow.example.org"]
+	if slow < 2 || slow > 3 || fast < 3*slow {
+		t.Errorf("fast host updated %d times, slow host %d times", fast, slow)
+	}
+}
+
+func TestMonitorMinSpacing(t *testing.T) {
+	updates := make(chan time.Time, 10)
+	m := &Monitor{
+		Client: newTestClient(t, "good 1.2.3.4"),
+		Hosts:  []HostConfig{{Hostname: hostname, MinSpacing: 80 * time.Millisecond}},
+		OnUpdate: func(Result) {
+			updates <- time.Now()
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+	first := <-updates
+	m.Trigger()
+	select {
+	case second := <-updates:
+		if d := second.Sub(first); d < 70*time.Millisecond {
+			t.Errorf("triggered update %v after the previous one, want about 80ms", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("postponed trigger never ran")
+	}
+}
+
+func TestMonitorSetHosts(t *testing.T) {
+	updates := make(chan string, 100)
+	m := &Monitor{
+		Client:   newTestClient(t, "good 1.2.3.4"),
+		Hosts:    []HostConfig{{Hostname: "a.example.org", Interval: 20 * time.Millisecond}},
+		OnUpdate: func(r Result) { updates <- r.Hostname },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+	if h := <-updates; h != "a.example.org" {
+		t.Fatalf("first update for %s", h)
+	}
+
+	m.SetHosts([]HostConfig{{Hostname: "b.example.org", Interval: 20 * time.Millisecond}})
+	deadline := time.After(time.Second)
+	for h := ""; h != "b.example.org"; {
+		select {
+		case h = <-updates:
+		case <-deadline:
+			t.Fatal("added host never updated")
+		}
+	}
+	// Drain an update of the removed host that may have raced with SetHosts.
+	time.Sleep(30 * time.Millisecond)
+	for len(updates) > 0 {
+		<-updates
+	}
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	<-done
+	close(updates)
+	var b int
+	for h := range updates {
+		if h != "b.example.org" {
+			t.Errorf("update for removed host %s", h)
+		}
+		b++
+	}
+	if b < 3 {
+		t.Errorf("added host updated %d times in 100ms, want about 5", b)
+	}
+}
+
+func TestMonitorEvents(t *testing.T) {
+	m := &Monitor{
+		Client:   newTestClient(t, "good 1.2.3.4"),
+		Hostname: hostname,
+		Interval: 20 * time.Millisecond,
+	}
+	events := m.Events()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now()
+	go m.Run(ctx)
+
+	var n int
+	for ev := range events {
+		if ctx.Err() != nil {
+			continue // an update cut short by cancel
+		}
+		if ev.Hostname != hostname || ev.Err != nil || ev.Code != CodeGood || !ev.IP.Equal(net.IPv4(1, 2, 3, 4)) {
+			t.Errorf("event %d = %+v", n, ev)
+		}
+		if ev.Time.Before(start) || ev.Time.After(time.Now()) {
+			t.Errorf("event %d at %v", n, ev.Time)
+		}
+		if n++; n == 3 {
+			cancel()
+		}
+	}
+}
+
+func TestMonitorEventsDropWhenFull(t *testing.T) {
+	m := &Monitor{Client: newTestClient(t, "good 1.2.3.4"), Hostname: hostname}
+	e := &hostEntry{HostConfig: HostConfig{Hostname: hostname}, index: -1}
+	events := m.Events()
+	for i := 0; i < EventBuffer+10; i++ {
+		m.update(context.Background(), e) // must not block
+	}
+	if n := len(events); n != EventBuffer {
+		t.Errorf("%d buffered events, want %d", n, EventBuffer)
+	}
+}
+
+func TestMonitorEventsRunTwice(t *testing.T) {
+	m := &Monitor{Client: newTestClient(t, "good 1.2.3.4"), Hostname: hostname}
+	for run := 0; run < 2; run++ {
+		events := m.Events()
+		done := make(chan error)
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() { done <- m.Run(ctx) }()
+		if ev := <-events; ev.Err != nil || ev.Code != CodeGood {
+			t.Errorf("run %d: event = %+v", run, ev)
+		}
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Errorf("run %d: Run returned %v", run, err)
+		}
+		for range events {
+		}
+	}
+}
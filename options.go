@@ -0,0 +1,320 @@
+package dyndns
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// An Option configures a Client created by NewClient.
+type Option func(*Client) error
+
+// NewClient returns a Client for the update service at rawurl. The Client
+// owns an HTTP client and transport, which the options configure.
+// The URL must use HTTPS unless AllowInsecure is given.
+func NewClient(rawurl string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("dyndns: invalid service URL: %w", err)
+	}
+	c := &Client{
+		URL: rawurl,
+		dialer: net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if u.Scheme != "https" && !c.insecure {
+		return nil, ErrInsecureURL
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.DialContext = c.dialContext
+	if len(c.pins) > 0 {
+		c.pinHost = u.Hostname()
+		tr.TLSClientConfig = &tls.Config{VerifyConnection: c.verifyPins}
+	}
+	for _, set := range c.transportOpts {
+		set(tr)
+	}
+	c.transport = tr
+	var rt http.RoundTripper = tr
+	for _, wrap := range c.wrappers {
+		rt = wrap(rt)
+	}
+	c.HTTPClient = &http.Client{Transport: rt}
+	return c, nil
+}
+
+// dialContext dials addr with the Client's dialer settings.
+func (c *Client) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if c.network != "" {
+		network = c.network
+	}
+	if c.dial != nil {
+		return c.dial(ctx, &c.dialer, network, addr)
+	}
+	return c.dialer.DialContext(ctx, network, addr)
+}
+
+// WithDialTimeout sets the maximum time to establish a TCP connection.
+// The default is 30 seconds; zero means no limit other than the context's.
+func WithDialTimeout(d time.Duration) Option {
+	return func(c *Client) error {
+		c.dialer.Timeout = d
+		return nil
+	}
+}
+
+// WithTLSHandshakeTimeout sets the maximum time for the TLS handshake
+// once connected. The default is http.DefaultTransport's; zero means no
+// limit.
+func WithTLSHandshakeTimeout(d time.Duration) Option {
+	return func(c *Client) error {
+		c.transportOpts = append(c.transportOpts, func(tr *http.Transport) {
+			tr.TLSHandshakeTimeout = d
+		})
+		return nil
+	}
+}
+
+// WithResponseHeaderTimeout sets the maximum time to wait for the
+// response headers after sending a request. By default there is no limit.
+func WithResponseHeaderTimeout(d time.Duration) Option {
+	return func(c *Client) error {
+		c.transportOpts = append(c.transportOpts, func(tr *http.Transport) {
+			tr.ResponseHeaderTimeout = d
+		})
+		return nil
+	}
+}
+
+// WithCredentials sets the provider of account credentials.
+func WithCredentials(p CredentialsProvider) Option {
+	return func(c *Client) error {
+		c.Credentials = p
+		return nil
+	}
+}
+
+// WithDialNetwork forces requests to the service over the given network:
+// "tcp4" for IPv4, "tcp6" for IPv6, or "tcp" for either. This controls
+// how the request travels, not which address is published.
+func WithDialNetwork(network string) Option {
+	return func(c *Client) error {
+		switch network {
+		case "tcp", "tcp4", "tcp6":
+			c.network = network
+			return nil
+		}
+		return fmt.Errorf("dyndns: unsupported dial network %q", network)
+	}
+}
+
+// VerifyWithCheckIP makes Update query DetectIP after each successful update
+// and add a warning to the result if the published address differs from the
+// detected one, as can happen with split-horizon DNS or caching proxies.
+func VerifyWithCheckIP() Option {
+	return func(c *Client) error {
+		c.verify = true
+		return nil
+	}
+}
+
+// WithLocalAddr makes requests originate from the local address ip, so on
+// a multi-homed host the service detects the intended address.
+func WithLocalAddr(ip net.IP) Option {
+	return func(c *Client) error {
+		c.dialer.LocalAddr = &net.TCPAddr{IP: ip}
+		return nil
+	}
+}
+
+// WithParamStyle sets where the hostname is placed in update requests.
+func WithParamStyle(style ParamStyle) Option {
+	return func(c *Client) error {
+		c.paramStyle = style
+		return nil
+	}
+}
+
+// ErrInsecureURL is returned by NewClient for a service URL that does not
+// use HTTPS, unless AllowInsecure is given.
+var ErrInsecureURL = errors.New("dyndns: service URL is not HTTPS")
+
+// AllowInsecure makes NewClient accept a service URL without HTTPS, such
+// as a self-hosted server on a trusted network. Credentials are then sent
+// in the clear.
+func AllowInsecure() Option {
+	return func(c *Client) error {
+		c.insecure = true
+		return nil
+	}
+}
+
+// WithSuccessCodes makes the Client treat codes as successful updates, in
+// addition to good and nochg, for compatible services with their own
+// return codes. As with good, an address following the code is parsed
+// into the result's IP.
+func WithSuccessCodes(codes ...Code) Option {
+	return func(c *Client) error {
+		if c.successCodes == nil {
+			c.successCodes = make(map[Code]bool)
+		}
+		for _, code := range codes {
+			c.successCodes[code] = true
+		}
+		return nil
+	}
+}
+
+// redacted replaces the values of secret headers passed to a request logger.
+const redacted = "REDACTED"
+
+// WithRequestLogger makes the Client call log with each update request
+// before it is sent, for troubleshooting malformed parameters. The URL
+// keeps its query, which is logged as is, but not a password in its user
+// information. The Authorization and Proxy-Authorization headers and those
+// added with WithHeader, which often hold API keys, are redacted. Headers
+// added by WithRoundTripper wrappers are not seen.
+func WithRequestLogger(log func(method, url string, headers http.Header)) Option {
+	return func(c *Client) error {
+		c.requestLogger = log
+		return nil
+	}
+}
+
+func (c *Client) logRequest(req *http.Request) {
+	h := req.Header.Clone()
+	for _, k := range []string{"Authorization", "Proxy-Authorization"} {
+		if _, ok := h[k]; ok {
+			h.Set(k, redacted)
+		}
+	}
+	for k := range c.header {
+		h.Set(k, redacted)
+	}
+	c.requestLogger(req.Method, req.URL.Redacted(), h)
+}
+
+// FailOnIPMismatch makes Update return an IPMismatchError, instead of
+// adding it to the result's warnings, when the server records a different
+// address than the one sent.
+func FailOnIPMismatch() Option {
+	return func(c *Client) error {
+		c.strictIP = true
+		return nil
+	}
+}
+
+// WithConditionalRequests makes Update send If-Modified-Since with the time
+// of the hostname's last successful update, and treat a 304 Not Modified
+// response as nochg. The header is sent only for updates that would leave
+// the address unchanged: with a nil ip or the last published one, and no
+// extra addresses or mail exchanger. Only use it with servers known to
+// support it.
+func WithConditionalRequests() Option {
+	return func(c *Client) error {
+		c.conditional = true
+		return nil
+	}
+}
+
+// WithTrace makes Update record how long each phase of the request took
+// in UpdateResult.Timings, to tell network delays from slow servers.
+func WithTrace() Option {
+	return func(c *Client) error {
+		c.trace = true
+		return nil
+	}
+}
+
+// DisableIDNA makes the Client send hostnames exactly as given, instead of
+// mapping them as for a DNS lookup, converting Unicode labels to Punycode
+// and rejecting names that are not valid.
+func DisableIDNA() Option {
+	return func(c *Client) error {
+		c.noIDNA = true
+		return nil
+	}
+}
+
+// WithHeader adds a header to every request sent to the service, such as
+// an API gateway key. It may be given several times, including for the same
+// key. A User-Agent header replaces UserAgent. Host and Authorization are
+// controlled by the URL and the credentials and cannot be set.
+func WithHeader(key, value string) Option {
+	return func(c *Client) error {
+		key = http.CanonicalHeaderKey(key)
+		if key == "Host" || key == "Authorization" {
+			return fmt.Errorf("dyndns: header %s cannot be set with WithHeader", key)
+		}
+		if c.header == nil {
+			c.header = make(http.Header)
+		}
+		c.header.Add(key, value)
+		return nil
+	}
+}
+
+// WithResponseFormat sets how the Client parses update responses.
+func WithResponseFormat(f ResponseFormat) Option {
+	return func(c *Client) error {
+		c.format = f
+		return nil
+	}
+}
+
+// WithRedundantIPPolicy sets what Update does when the address to send is
+// already the one the server has for the hostname.
+func WithRedundantIPPolicy(p RedundantIPPolicy) Option {
+	return func(c *Client) error {
+		c.redundantIP = p
+		return nil
+	}
+}
+
+// DetectFamily makes the service detect the client's address of the given
+// family, 4 or 6, on dual-stack hosts. Requests are sent over that family
+// as with WithDialNetwork, and updates that leave detection to the server
+// report the family in UpdateResult.Family.
+func DetectFamily(family int) Option {
+	return func(c *Client) error {
+		network, err := familyNetwork(family)
+		if err != nil {
+			return err
+		}
+		c.network, c.family = network, family
+		return nil
+	}
+}
+
+// KeepResponseHeaders makes Update return the HTTP response headers in
+// UpdateResult.Header, for diagnosing provider behavior. They are not kept
+// by default to avoid retaining memory.
+func KeepResponseHeaders() Option {
+	return func(c *Client) error {
+		c.keepHeaders = true
+		return nil
+	}
+}
+
+// WithRoundTripper wraps the Client's transport, for example to sign
+// requests for providers that need more than basic authentication. wrap
+// receives the next RoundTripper in the chain and returns one that calls
+// it. When given several times, the last wrapper is outermost.
+func WithRoundTripper(wrap func(http.RoundTripper) http.RoundTripper) Option {
+	return func(c *Client) error {
+		c.wrappers = append(c.wrappers, wrap)
+		return nil
+	}
+}
@@ -0,0 +1,346 @@
+package dyndns
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithDialNetwork(t *testing.T) {
+	srv := newTestServer(t, "good 1.2.3.4", nil, nil)
+	c, err := NewClient(srv.URL, AllowInsecure(), WithDialNetwork("tcp4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var networks []string
+	c.dial = func(ctx context.Context, d *net.Dialer, network, addr string) (net.Conn, error) {
+		networks = append(networks, network)
+		return d.DialContext(ctx, network, addr)
+	}
+	if _, err := c.Update(context.Background(), hostname, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(networks) != 1 || networks[0] != "tcp4" {
+		t.Errorf("dialed networks = %q, want [tcp4]", networks)
+	}
+}
+
+func TestWithDialNetworkInvalid(t *testing.T) {
+	if _, err := NewClient(DynDNS, WithDialNetwork("udp")); err == nil {
+		t.Error("expected error for udp")
+	}
+}
+
+func TestWithLocalAddr(t *testing.T) {
+	srv := newTestServer(t, "good 1.2.3.4", nil, nil)
+	local := net.IPv4(127, 0, 0, 1)
+	c, err := NewClient(srv.URL, AllowInsecure(), WithLocalAddr(local))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addrs []net.Addr
+	c.dial = func(ctx context.Context, d *net.Dialer, network, addr string) (net.Conn, error) {
+		addrs = append(addrs, d.LocalAddr)
+		return d.DialContext(ctx, network, addr)
+	}
+	if _, err := c.Update(context.Background(), hostname, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 {
+		t.Fatalf("dialed %d times, want 1", len(addrs))
+	}
+	if a, ok := addrs[0].(*net.TCPAddr); !ok || !a.IP.Equal(local) {
+		t.Errorf("LocalAddr = %v, want %v", addrs[0], local)
+	}
+}
+
+func TestWithHeader(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+	c, err := NewClient(srv.URL, AllowInsecure(),
+		WithCredentials(StaticCredentials(username, password)),
+		WithHeader("x-api-key", "secret"),
+		WithHeader("X-Trace", "a"),
+		WithHeader("X-Trace", "b"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Update(context.Background(), hostname, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got.Get("X-Api-Key") != "secret" || len(got["X-Trace"]) != 2 {
+		t.Errorf("headers = %v", got)
+	}
+	if got.Get("User-Agent") != UserAgent {
+		t.Errorf("User-Agent = %q", got.Get("User-Agent"))
+	}
+	if user, _, ok := (&http.Request{Header: got}).BasicAuth(); !ok || user != username {
+		t.Error("credentials not sent")
+	}
+
+	c, _ = NewClient(srv.URL, AllowInsecure(), WithHeader("User-Agent", "example-updater/1.0"))
+	c.Update(context.Background(), hostname, nil)
+	if got.Get("User-Agent") != "example-updater/1.0" {
+		t.Errorf("User-Agent = %q, want override", got.Get("User-Agent"))
+	}
+}
+
+func TestWithHeaderReserved(t *testing.T) {
+	for _, key := range []string{"Host", "authorization"} {
+		if _, err := NewClient(DynDNS, WithHeader(key, "x")); err == nil {
+			t.Errorf("WithHeader(%q) accepted", key)
+		}
+	}
+}
+
+func TestDetectFamily(t *testing.T) {
+	srv := newTestServer(t, "good 1.2.3.4", nil, nil)
+	c, err := NewClient(srv.URL, AllowInsecure(), DetectFamily(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var networks []string
+	c.dial = func(ctx context.Context, d *net.Dialer, network, addr string) (net.Conn, error) {
+		networks = append(networks, network)
+		return d.DialContext(ctx, network, addr)
+	}
+	res, err := c.Update(context.Background(), hostname, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networks) != 1 || networks[0] != "tcp4" {
+		t.Errorf("dialed networks = %q, want [tcp4]", networks)
+	}
+	if res.Family != 4 {
+		t.Errorf("Family = %d, want 4", res.Family)
+	}
+	if res, _ := c.Update(context.Background(), hostname, net.IPv4(1, 2, 3, 4)); res.Family != 0 {
+		t.Errorf("explicit address: Family = %d, want 0", res.Family)
+	}
+
+	c, _ = NewClient(srv.URL, AllowInsecure(), DetectFamily(6))
+	if c.network != "tcp6" {
+		t.Errorf("DetectFamily(6) network = %q", c.network)
+	}
+	if _, err := NewClient(srv.URL, AllowInsecure(), DetectFamily(5)); err == nil {
+		t.Error("DetectFamily(5) accepted")
+	}
+}
+
+func TestKeepResponseHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-By", "dc-1")
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+	c, _ := NewClient(srv.URL, AllowInsecure())
+	if res, _ := c.Update(context.Background(), hostname, nil); res.Header != nil {
+		t.Error("headers kept by default")
+	}
+	c, _ = NewClient(srv.URL, AllowInsecure(), KeepResponseHeaders())
+	res, err := c.Update(context.Background(), hostname, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Header.Get("X-Served-By"); got != "dc-1" {
+		t.Errorf("X-Served-By = %q", got)
+	}
+}
+
+// signer is an example RoundTripper that signs the query and a timestamp
+// with HMAC-SHA256, as some providers require.
+type signer struct {
+	next   http.RoundTripper
+	secret []byte
+}
+
+func (s *signer) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("X-Timestamp", ts)
+	req.Header.Set("X-Signature", sign(s.secret, req.URL.RawQuery, ts))
+	return s.next.RoundTrip(req)
+}
+
+func sign(secret []byte, query, ts string) string {
+	mac := hmac.New(sha256.New, secret)
+	io.WriteString(mac, query+"\n"+ts)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWithRoundTripper(t *testing.T) {
+	secret := []byte("shared secret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := sign(secret, r.URL.RawQuery, r.Header.Get("X-Timestamp"))
+		if r.Header.Get("X-Signature") != want {
+			io.WriteString(w, "badauth")
+			return
+		}
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+
+	var order []string
+	trace := func(name string) func(http.RoundTripper) http.RoundTripper {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.RoundTrip(r)
+			})
+		}
+	}
+	c, err := NewClient(srv.URL, AllowInsecure(),
+		WithRoundTripper(func(next http.RoundTripper) http.RoundTripper {
+			return &signer{next, secret}
+		}),
+		WithRoundTripper(trace("outer")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Update(context.Background(), hostname, nil); err != nil {
+		t.Errorf("signed request: %v", err)
+	}
+	if len(order) != 1 {
+		t.Errorf("outer wrapper called %d times", len(order))
+	}
+
+	c, _ = NewClient(srv.URL, AllowInsecure())
+	if _, err := c.Update(context.Background(), hostname, nil); err != ErrAuth {
+		t.Errorf("unsigned request: err = %v, want badauth", err)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestAllowInsecure(t *testing.T) {
+	for _, u := range []string{"http://example.com/nic/update", "ftp://example.com/", "example.com/nic/update"} {
+		if _, err := NewClient(u); err != ErrInsecureURL {
+			t.Errorf("%q: err = %v, want ErrInsecureURL", u, err)
+		}
+		if _, err := NewClient(u, AllowInsecure()); err != nil {
+			t.Errorf("%q with AllowInsecure: %v", u, err)
+		}
+	}
+	for _, u := range []string{DynDNS, "HTTPS://example.com/nic/update"} {
+		if _, err := NewClient(u); err != nil {
+			t.Errorf("%q: %v", u, err)
+		}
+	}
+}
+
+func TestWithSuccessCodes(t *testing.T) {
+	srv := newTestServer(t, "updated 1.2.3.4\n", nil, nil)
+	ctx := context.Background()
+
+	c, _ := NewClient(srv.URL, AllowInsecure())
+	if _, err := c.Update(ctx, hostname, nil); err == nil {
+		t.Error("custom code accepted without WithSuccessCodes")
+	}
+
+	c, err := NewClient(srv.URL, AllowInsecure(), WithSuccessCodes("updated", "done"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Update(ctx, hostname, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != "updated" || !res.IP.Equal(net.IPv4(1, 2, 3, 4)) {
+		t.Errorf("got %q %v, want updated 1.2.3.4", res.Code, res.IP)
+	}
+	if _, ip := c.lastUpdate(hostname); !ip.Equal(net.IPv4(1, 2, 3, 4)) {
+		t.Errorf("recorded address %v", ip)
+	}
+}
+
+func TestWithRequestLogger(t *testing.T) {
+	srv := newTestServer(t, "good 1.2.3.4", nil, nil)
+	var method, logged string
+	var headers http.Header
+	c, err := NewClient(strings.Replace(srv.URL, "http://", "http://user:hunter2@", 1),
+		AllowInsecure(),
+		WithCredentials(StaticCredentials(username, "s3cret")),
+		WithHeader("X-Api-Key", "k3y"),
+		WithRequestLogger(func(m, u string, h http.Header) {
+			method, logged, headers = m, u, h
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Update(context.Background(), hostname, net.IPv4(1, 2, 3, 4), WithTXT("x y")); err != nil {
+		t.Fatal(err)
+	}
+	if method != "GET" {
+		t.Errorf("method = %q", method)
+	}
+	u, err := url.Parse(logged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("hostname") != hostname || q.Get("myip") != "1.2.3.4" || q.Get("txt") != "x y" {
+		t.Errorf("query = %v", q)
+	}
+	if got := headers.Get("Authorization"); got != redacted {
+		t.Errorf("Authorization = %q", got)
+	}
+	if got := headers.Get("X-Api-Key"); got != redacted {
+		t.Errorf("X-Api-Key = %q", got)
+	}
+	if headers.Get("User-Agent") != UserAgent {
+		t.Errorf("headers = %v", headers)
+	}
+	all := logged + fmt.Sprint(headers)
+	for _, secret := range []string{"s3cret", "hunter2", "k3y", base64.StdEncoding.EncodeToString([]byte(username + ":s3cret"))} {
+		if strings.Contains(all, secret) {
+			t.Errorf("logged secret %q: %s", secret, all)
+		}
+	}
+}
+
+func TestTimeoutOptions(t *testing.T) {
+	c, err := NewClient(DynDNS,
+		WithDialTimeout(5*time.Second),
+		WithTLSHandshakeTimeout(20*time.Second),
+		WithResponseHeaderTimeout(3*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.dialer.Timeout != 5*time.Second {
+		t.Errorf("dial timeout = %v", c.dialer.Timeout)
+	}
+	if c.transport.TLSHandshakeTimeout != 20*time.Second {
+		t.Errorf("TLS handshake timeout = %v", c.transport.TLSHandshakeTimeout)
+	}
+	if c.transport.ResponseHeaderTimeout != 3*time.Second {
+		t.Errorf("response header timeout = %v", c.transport.ResponseHeaderTimeout)
+	}
+
+	c, _ = NewClient(DynDNS)
+	def := http.DefaultTransport.(*http.Transport)
+	if c.dialer.Timeout != 30*time.Second || c.transport.TLSHandshakeTimeout != def.TLSHandshakeTimeout || c.transport.ResponseHeaderTimeout != 0 {
+		t.Errorf("defaults: dial %v, TLS %v, header %v", c.dialer.Timeout, c.transport.TLSHandshakeTimeout, c.transport.ResponseHeaderTimeout)
+	}
+}
@@ -0,0 +1,47 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTooSoon is returned, with RejectTooSoon, for an update that would be
+// sent less than MinInterval after the previous one.
+var ErrTooSoon = errors.New("dyndns: update too soon after the previous one")
+
+// RejectTooSoon makes updates that would break the Client's MinInterval
+// fail with ErrTooSoon instead of waiting for their turn.
+func RejectTooSoon() Option {
+	return func(c *Client) error {
+		c.rejectTooSoon = true
+		return nil
+	}
+}
+
+// pace waits until the next update request may be sent under MinInterval
+// and takes that turn. The turn starts when pace returns rather than when
+// it was reserved, so a waiter that wakes up late cannot shorten the gap
+// before the next request.
+func (c *Client) pace(ctx context.Context) error {
+	if c.MinInterval <= 0 {
+		return nil
+	}
+	for {
+		c.paceMu.Lock()
+		now := time.Now()
+		wait := c.nextRequest.Sub(now)
+		if wait <= 0 {
+			c.nextRequest = now.Add(c.MinInterval)
+			c.paceMu.Unlock()
+			return nil
+		}
+		c.paceMu.Unlock()
+		if c.rejectTooSoon {
+			return ErrTooSoon
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package dyndns
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMinInterval(t *testing.T) {
+	srv := newTestServer(t, "good 1.2.3.4", nil, nil)
+	const interval = 50 * time.Millisecond
+	// Record when requests leave the Client rather than when they reach
+	// the server, which also depends on connection setup.
+	var mu sync.Mutex
+	var times []time.Time
+	c := &Client{URL: srv.URL, MinInterval: interval}
+	c.HTTPClient = &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+
+	var wg sync.WaitGroup
+	for _, h := range []string{"a.dyndns.org", "b.dyndns.org", "c.dyndns.org", "d.dyndns.org"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Update(context.Background(), h, nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(times) != 4 {
+		t.Fatalf("%d requests, want 4", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		if d := times[i].Sub(times[i-1]); d < interval-5*time.Millisecond {
+			t.Errorf("request %d sent %v after the previous one, want at least %v", i, d, interval)
+		}
+	}
+}
+
+func TestRejectTooSoon(t *testing.T) {
+	srv := newTestServer(t, "good 1.2.3.4", nil, nil)
+	c, err := NewClient(srv.URL, AllowInsecure(), RejectTooSoon())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.MinInterval = time.Hour
+	ctx := context.Background()
+	if _, err := c.Update(ctx, hostname, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Update(ctx, "other.dyndns.org", nil); err != ErrTooSoon {
+		t.Errorf("err = %v, want ErrTooSoon", err)
+	}
+}
@@ -0,0 +1,152 @@
+package dyndns
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// A ParamStyle is a way of placing the hostname in an update request.
+type ParamStyle int
+
+const (
+	// QueryParams sends the hostname as a query parameter:
+	// /nic/update?hostname=host. This is the standard form.
+	QueryParams ParamStyle = iota
+
+	// PathHostname appends the hostname to the path:
+	// /nic/update/host. A few compatible servers expect this form.
+	PathHostname
+)
+
+// requestURL returns the update URL for hostname with the given query.
+func (c *Client) requestURL(hostname string, query url.Values) string {
+	u := c.URL
+	if c.paramStyle == PathHostname {
+		u = strings.TrimSuffix(u, "/") + "/" + url.PathEscape(hostname)
+		query.Del("hostname")
+	} else {
+		query.Set("hostname", hostname)
+	}
+	return u + "?" + query.Encode()
+}
+
+// An UpdateOption sets an optional parameter of a single update request.
+type UpdateOption func(*updateParams) error
+
+// updateParams holds the optional query parameters and headers of an
+// update request.
+type updateParams struct {
+	query  url.Values
+	header http.Header
+	mail   bool // whether mail exchanger parameters are set
+
+	extraIPs   []net.IP // addresses sent after the update's ip, for UpdateIPs
+	bestEffort bool     // whether UpdateDual may publish a single family
+
+	warnings []error       // raised while sending, added to each result
+	changes  *[]hostChange // if set, collects changes instead of OnChange
+}
+
+// hostChange is a change of address to report to OnChange.
+type hostChange struct {
+	hostname string
+	old, new net.IP
+}
+
+func newUpdateParams(opts []UpdateOption) (*updateParams, error) {
+	p := &updateParams{query: make(url.Values), header: make(http.Header)}
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// maxTXTLen is the maximum length of a single TXT character-string.
+const maxTXTLen = 255
+
+// WithTXT sets a TXT record value alongside the address update, as used for
+// ACME DNS-01 challenges. Support for the txt parameter is provider-dependent;
+// servers that do not recognize it ignore it or reply with badsys.
+func WithTXT(value string) UpdateOption {
+	return func(p *updateParams) error {
+		if len(value) > maxTXTLen {
+			return fmt.Errorf("dyndns: TXT value is %d bytes, limit is %d", len(value), maxTXTLen)
+		}
+		p.query.Set("txt", value)
+		return nil
+	}
+}
+
+// WithMX sets the hostname's mail exchanger. Combined with a nil ip, the
+// update is a mail-only update: the Client does not treat it as asking the
+// server to detect an address, so DetectFamily and VerifyWithCheckIP do
+// not apply. Servers still require the hostname, and most echo its
+// unchanged address; some may still apply the address the request came
+// from, so check the provider's behavior.
+func WithMX(mx string) UpdateOption {
+	return func(p *updateParams) error {
+		p.query.Set("mx", mx)
+		p.mail = true
+		return nil
+	}
+}
+
+// WithBackMX sets whether the mail exchanger is also a backup MX for the
+// hostname. See WithMX for mail-only updates.
+func WithBackMX(on bool) UpdateOption {
+	return func(p *updateParams) error {
+		v := "NO"
+		if on {
+			v = "YES"
+		}
+		p.query.Set("backmx", v)
+		p.mail = true
+		return nil
+	}
+}
+
+// A WildcardState is a value of the wildcard parameter, which controls
+// whether *.hostname resolves like hostname.
+type WildcardState int
+
+const (
+	// WildcardNoChange keeps the current wildcard setting.
+	WildcardNoChange WildcardState = iota
+
+	// WildcardOn enables the wildcard.
+	WildcardOn
+
+	// WildcardOff disables the wildcard.
+	WildcardOff
+)
+
+var wildcardValues = [...]string{
+	WildcardNoChange: "NOCHG",
+	WildcardOn:       "ON",
+	WildcardOff:      "OFF",
+}
+
+// String returns the wire value of w.
+func (w WildcardState) String() string {
+	if w < 0 || int(w) >= len(wildcardValues) {
+		return fmt.Sprintf("WildcardState(%d)", int(w))
+	}
+	return wildcardValues[w]
+}
+
+// Wildcard sets the hostname's wildcard state. Without this option the
+// parameter is not sent.
+func Wildcard(w WildcardState) UpdateOption {
+	return func(p *updateParams) error {
+		if w < 0 || int(w) >= len(wildcardValues) {
+			return fmt.Errorf("dyndns: invalid wildcard state %d", int(w))
+		}
+		p.query.Set("wildcard", wildcardValues[w])
+		return nil
+	}
+}
@@ -0,0 +1,157 @@
+package dyndns
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestWithTXT(t *testing.T) {
+	var q url.Values
+	c := &Client{URL: newTestServer(t, "good 1.2.3.4", nil, &q).URL}
+	const value = "abc=def&ghi jkl"
+	if _, err := c.Update(context.Background(), hostname, nil, WithTXT(value)); err != nil {
+		t.Fatal(err)
+	}
+	if got := q.Get("txt"); got != value {
+		t.Errorf("txt = %q, want %q", got, value)
+	}
+	if got := q.Get("hostname"); got != hostname {
+		t.Errorf("hostname = %q, want %q", got, hostname)
+	}
+}
+
+func TestWithTXTTooLong(t *testing.T) {
+	var q url.Values
+	c := &Client{URL: newTestServer(t, "good 1.2.3.4", nil, &q).URL}
+	_, err := c.Update(context.Background(), hostname, nil, WithTXT(strings.Repeat("x", 256)))
+	if err == nil {
+		t.Error("expected error for 256-byte TXT value")
+	}
+	if q != nil {
+		t.Error("request sent despite invalid TXT value")
+	}
+}
+
+func TestParamStyle(t *testing.T) {
+	var path string
+	var q url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, q = r.URL.EscapedPath(), r.URL.Query()
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+	for _, tt := range []struct {
+		style    ParamStyle
+		host     string
+		path     string
+		hostname string
+	}{
+		{QueryParams, hostname, "/nic/update", hostname},
+		{QueryParams, "a b&c", "/nic/update", "a b&c"},
+		{PathHostname, hostname, "/nic/update/" + hostname, ""},
+		{PathHostname, "a b/c?", "/nic/update/a%20b%2Fc%3F", ""},
+	} {
+		// Hostnames that are not valid DNS names must bypass IDNA
+		// validation to reach the escaping.
+		c, err := NewClient(srv.URL+"/nic/update", AllowInsecure(), WithParamStyle(tt.style), DisableIDNA())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Update(context.Background(), tt.host, nil); err != nil {
+			t.Fatal(err)
+		}
+		if path != tt.path || q.Get("hostname") != tt.hostname {
+			t.Errorf("style %d, host %q: path %q, hostname %q; want %q, %q",
+				tt.style, tt.host, path, q.Get("hostname"), tt.path, tt.hostname)
+		}
+	}
+}
+
+func TestDefaultFormatIP(t *testing.T) {
+	for _, tt := range []struct {
+		ip   net.IP
+		want string
+	}{
+		{net.ParseIP("::ffff:1.2.3.4"), "1.2.3.4"},
+		{net.IP{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 1, 2, 3, 4}, "1.2.3.4"},
+		{net.ParseIP("fe80::1"), "fe80::1"},
+		{net.ParseIP("2001:db8::1"), "2001:db8::1"},
+		{net.IPv4(1, 2, 3, 4).To4(), "1.2.3.4"},
+	} {
+		if got := DefaultFormatIP(tt.ip); got != tt.want {
+			t.Errorf("DefaultFormatIP(%v) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestFormatIPHook(t *testing.T) {
+	var q url.Values
+	c := &Client{URL: newTestServer(t, "good 1.2.3.4", nil, &q).URL}
+	c.FormatIP = func(ip net.IP) string { return "[" + ip.String() + "]" }
+	c.Update(context.Background(), hostname, net.ParseIP("2001:db8::1"))
+	if got := q.Get("myip"); got != "[2001:db8::1]" {
+		t.Errorf("myip = %q", got)
+	}
+	c.FormatIP = nil
+	c.Update(context.Background(), hostname, net.ParseIP("::ffff:1.2.3.4"))
+	if got := q.Get("myip"); got != "1.2.3.4" {
+		t.Errorf("default myip = %q", got)
+	}
+}
+
+func TestMailOnlyUpdate(t *testing.T) {
+	var q url.Values
+	srv := newTestServer(t, "nochg 1.2.3.4", nil, &q)
+	c, err := NewClient(srv.URL, AllowInsecure(), DetectFamily(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Update(context.Background(), hostname, nil, WithMX("mail.example.org"), WithBackMX(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Get("mx") != "mail.example.org" || q.Get("backmx") != "YES" || q.Has("myip") {
+		t.Errorf("query = %v", q)
+	}
+	if res.Family != 0 || !res.IP.Equal(net.IPv4(1, 2, 3, 4)) {
+		t.Errorf("result = %+v, want echoed address and no detected family", res)
+	}
+	c.Update(context.Background(), hostname, nil, WithBackMX(false))
+	if q.Get("backmx") != "NO" {
+		t.Errorf("backmx = %q", q.Get("backmx"))
+	}
+}
+
+func TestWildcard(t *testing.T) {
+	var q url.Values
+	c := &Client{URL: newTestServer(t, "good 1.2.3.4", nil, &q).URL}
+	ctx := context.Background()
+	for w, want := range map[WildcardState]string{
+		WildcardNoChange: "NOCHG",
+		WildcardOn:       "ON",
+		WildcardOff:      "OFF",
+	} {
+		if _, err := c.Update(ctx, hostname, nil, Wildcard(w)); err != nil {
+			t.Fatal(err)
+		}
+		if got := q.Get("wildcard"); got != want {
+			t.Errorf("%v: wildcard = %q, want %q", w, got, want)
+		}
+	}
+
+	c.Update(ctx, hostname, nil)
+	if _, ok := q["wildcard"]; ok {
+		t.Errorf("wildcard sent by default: %q", q.Get("wildcard"))
+	}
+
+	q = nil
+	if _, err := c.Update(ctx, hostname, nil, Wildcard(WildcardState(7))); err == nil || q != nil {
+		t.Errorf("invalid state: err = %v, request sent: %v", err, q != nil)
+	}
+}
@@ -0,0 +1,77 @@
+package dyndns
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrCertPinMismatch is returned when the service's certificate chain
+// matches none of the pins given to WithPinnedCert.
+var ErrCertPinMismatch = errors.New("dyndns: certificate does not match any pinned fingerprint")
+
+// WithPinnedCert makes the Client accept a TLS connection to the service
+// only if a certificate in the verified chain matches one of the pins,
+// in addition to the usual verification. Other hosts the Client talks
+// to, such as CheckIPURL, are not pinned. A pin is the SHA-256 digest of a
+// certificate or of its public key (SubjectPublicKeyInfo), written in hex,
+// with or without colons, or in base64.
+func WithPinnedCert(fingerprints ...string) Option {
+	return func(c *Client) error {
+		for _, fp := range fingerprints {
+			pin, err := parsePin(fp)
+			if err != nil {
+				return err
+			}
+			c.pins = append(c.pins, pin)
+		}
+		return nil
+	}
+}
+
+func parsePin(fp string) ([]byte, error) {
+	s := strings.TrimPrefix(fp, "sha256/")
+	if pin, err := hex.DecodeString(strings.ReplaceAll(s, ":", "")); err == nil && len(pin) == sha256.Size {
+		return pin, nil
+	}
+	if pin, err := base64.StdEncoding.DecodeString(s); err == nil && len(pin) == sha256.Size {
+		return pin, nil
+	}
+	return nil, fmt.Errorf("dyndns: invalid SHA-256 fingerprint %q", fp)
+}
+
+// verifyPins is a tls.Config VerifyConnection callback checking the
+// verified chains of connections to the service against the Client's pins.
+// Certificates the peer sent that are not part of a verified chain do not
+// count. Connections to other hosts, such as CheckIPURL, are not pinned:
+// a connection is to the service if its server name is the service URL's
+// host. An IP address host is sent no server name, so then the connection
+// is to the service if its certificate is valid for that address.
+func (c *Client) verifyPins(cs tls.ConnectionState) error {
+	if !strings.EqualFold(cs.ServerName, c.pinHost) {
+		if cs.ServerName != "" || net.ParseIP(c.pinHost) == nil {
+			return nil
+		}
+		if len(cs.PeerCertificates) == 0 || cs.PeerCertificates[0].VerifyHostname(c.pinHost) != nil {
+			return nil
+		}
+	}
+	for _, chain := range cs.VerifiedChains {
+		for _, cert := range chain {
+			certSum := sha256.Sum256(cert.Raw)
+			keySum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			for _, pin := range c.pins {
+				if bytes.Equal(pin, certSum[:]) || bytes.Equal(pin, keySum[:]) {
+					return nil
+				}
+			}
+		}
+	}
+	return ErrCertPinMismatch
+}
@@ -0,0 +1,192 @@
+package dyndns
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithPinnedCert(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+	cert := srv.Certificate()
+	certSum := sha256.Sum256(cert.Raw)
+	keySum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	other := sha256.Sum256([]byte("some other certificate"))
+
+	colons := strings.ToUpper(hex.EncodeToString(certSum[:]))
+	for i := len(colons) - 2; i > 0; i -= 2 {
+		colons = colons[:i] + ":" + colons[i:]
+	}
+	for _, tt := range []struct {
+		pins []string
+		ok   bool
+	}{
+		{[]string{hex.EncodeToString(certSum[:])}, true},
+		{[]string{colons}, true},
+		{[]string{"sha256/" + base64.StdEncoding.EncodeToString(keySum[:])}, true},
+		{[]string{hex.EncodeToString(other[:]), hex.EncodeToString(keySum[:])}, true},
+		{[]string{hex.EncodeToString(other[:])}, false},
+	} {
+		c, err := NewClient(srv.URL, WithPinnedCert(tt.pins...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		roots := x509.NewCertPool()
+		roots.AddCert(cert)
+		c.transport.TLSClientConfig.RootCAs = roots
+
+		_, err = c.Update(context.Background(), hostname, nil)
+		if tt.ok && err != nil {
+			t.Errorf("pins %q: %v", tt.pins, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrCertPinMismatch) {
+			t.Errorf("pins %q: err = %v, want ErrCertPinMismatch", tt.pins, err)
+		}
+		if !tt.ok && IsTransient(err) {
+			t.Errorf("pin mismatch reported as transient")
+		}
+	}
+}
+
+func TestWithPinnedCertInvalid(t *testing.T) {
+	for _, fp := range []string{"", "abc", strings.Repeat("zz", 32)} {
+		if _, err := NewClient(DynDNS, WithPinnedCert(fp)); err == nil {
+			t.Errorf("fingerprint %q accepted", fp)
+		}
+	}
+}
+
+// issueCert returns a certificate for name signed by parent, or a
+// self-signed CA certificate if parent is nil.
+func issueCert(t *testing.T, name string, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	} else {
+		tmpl.DNSNames = []string{name}
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// newPinServer returns a TLS test server replying with body that presents
+// the given chain.
+func newPinServer(t *testing.T, body string, key crypto.Signer, chain ...*x509.Certificate) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	cert := tls.Certificate{PrivateKey: key}
+	for _, c := range chain {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newPinClient returns a Client for https://update.test/ trusting ca, with
+// connections to the hosts in servers routed to the matching test server.
+func newPinClient(t *testing.T, ca *x509.Certificate, servers map[string]*httptest.Server, opts ...Option) *Client {
+	c, err := NewClient("https://update.test/", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.dial = func(ctx context.Context, d *net.Dialer, network, addr string) (net.Conn, error) {
+		host, _, _ := net.SplitHostPort(addr)
+		if srv := servers[host]; srv != nil {
+			addr = srv.Listener.Addr().String()
+		}
+		return d.DialContext(ctx, network, addr)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	c.transport.TLSClientConfig.RootCAs = roots
+	return c
+}
+
+func pinOf(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestWithPinnedCertVerifiedChain(t *testing.T) {
+	ca, caKey := issueCert(t, "Test CA", nil, nil)
+	leaf, leafKey := issueCert(t, "update.test", ca, caKey)
+	pinned, _ := issueCert(t, "Pinned CA", nil, nil)
+	// The server sends the pinned certificate along with a leaf it did
+	// not issue, which must not satisfy the pin.
+	srv := newPinServer(t, "good 1.2.3.4", leafKey, leaf, pinned)
+	servers := map[string]*httptest.Server{"update.test": srv}
+
+	c := newPinClient(t, ca, servers, WithPinnedCert(pinOf(pinned)))
+	if _, err := c.Update(context.Background(), hostname, nil); !errors.Is(err, ErrCertPinMismatch) {
+		t.Errorf("unverified pinned certificate: err = %v, want ErrCertPinMismatch", err)
+	}
+	c = newPinClient(t, ca, servers, WithPinnedCert(pinOf(ca)))
+	if _, err := c.Update(context.Background(), hostname, nil); err != nil {
+		t.Errorf("pinned root: %v", err)
+	}
+}
+
+func TestWithPinnedCertOtherHosts(t *testing.T) {
+	ca, caKey := issueCert(t, "Test CA", nil, nil)
+	leaf, leafKey := issueCert(t, "update.test", ca, caKey)
+	checkLeaf, checkKey := issueCert(t, "checkip.test", ca, caKey)
+	servers := map[string]*httptest.Server{
+		"update.test":  newPinServer(t, "good 1.2.3.4", leafKey, leaf),
+		"checkip.test": newPinServer(t, "1.2.3.4", checkKey, checkLeaf),
+	}
+	c := newPinClient(t, ca, servers, WithPinnedCert(pinOf(leaf)))
+	c.CheckIPURL = "https://checkip.test/"
+	ctx := context.Background()
+	if _, err := c.Update(ctx, hostname, nil); err != nil {
+		t.Errorf("Update: %v", err)
+	}
+	if _, err := c.ForceDetectIP(ctx); err != nil {
+		t.Errorf("ForceDetectIP: %v", err)
+	}
+	if _, err := c.DetectIPFamily(ctx, 4); err != nil {
+		t.Errorf("DetectIPFamily: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package dyndns
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimit holds the rate-limit state reported by a provider in
+// X-RateLimit-* response headers.
+type RateLimit struct {
+	Limit     int       // requests allowed in the current window, or -1 if not sent
+	Remaining int       // requests left in the current window, or -1 if not sent
+	Reset     time.Time // when the window resets, or zero if not sent
+}
+
+// parseRateLimit returns the rate limit described by h, or nil if h has
+// no rate-limit headers. X-RateLimit-Reset may be a Unix time or a number
+// of seconds from now; values before 2001 are taken as the latter.
+func parseRateLimit(h http.Header, now time.Time) *RateLimit {
+	rl := &RateLimit{Limit: -1, Remaining: -1}
+	found := false
+	if n, err := strconv.Atoi(h.Get("X-RateLimit-Limit")); err == nil {
+		rl.Limit, found = n, true
+	}
+	if n, err := strconv.Atoi(h.Get("X-RateLimit-Remaining")); err == nil {
+		rl.Remaining, found = n, true
+	}
+	if n, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		if n < 1e9 {
+			rl.Reset = now.Add(time.Duration(n) * time.Second)
+		} else {
+			rl.Reset = time.Unix(n, 0)
+		}
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	return rl
+}
+
+// ErrRateLimited matches a RateLimitedError with errors.Is.
+var ErrRateLimited = errors.New("dyndns: rate limited")
+
+// A RateLimitedError is returned for an HTTP 429 Too Many Requests
+// response. It is transient; with WithRetry, the next attempt waits at
+// least RetryAfter.
+type RateLimitedError struct {
+	RetryAfter time.Duration // from the Retry-After header, or zero if not sent
+}
+
+// Error satisfies the built-in error interface.
+func (e *RateLimitedError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%v: retry after %v", ErrRateLimited, e.RetryAfter)
+	}
+	return ErrRateLimited.Error()
+}
+
+// Is reports whether target is ErrRateLimited.
+func (e *RateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// parseRetryAfter returns the delay in a Retry-After header, given as
+// seconds or as an HTTP date, or zero if it is absent or invalid.
+func parseRetryAfter(h http.Header, now time.Time) time.Duration {
+	v := h.Get("Retry-After")
+	if n, err := strconv.Atoi(v); err == nil && n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,117 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	for _, tt := range []struct {
+		header map[string]string
+		want   *RateLimit
+	}{
+		{nil, nil},
+		{map[string]string{"X-RateLimit-Remaining": "bogus"}, nil},
+		{
+			map[string]string{"X-RateLimit-Remaining": "3"},
+			&RateLimit{Limit: -1, Remaining: 3},
+		},
+		{
+			map[string]string{"X-RateLimit-Limit": "10", "X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "60"},
+			&RateLimit{Limit: 10, Remaining: 0, Reset: now.Add(time.Minute)},
+		},
+		{
+			map[string]string{"X-RateLimit-Reset": "1700000300"},
+			&RateLimit{Limit: -1, Remaining: -1, Reset: time.Unix(1700000300, 0)},
+		},
+	} {
+		h := make(http.Header)
+		for k, v := range tt.header {
+			h.Set(k, v)
+		}
+		got := parseRateLimit(h, now)
+		if (got == nil) != (tt.want == nil) || got != nil &&
+			(got.Limit != tt.want.Limit || got.Remaining != tt.want.Remaining || !got.Reset.Equal(tt.want.Reset)) {
+			t.Errorf("%v: got %+v, want %+v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "7")
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+	res, err := (&Client{URL: srv.URL}).Update(context.Background(), hostname, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.RateLimit == nil || res.RateLimit.Remaining != 7 {
+		t.Errorf("RateLimit = %+v, want Remaining 7", res.RateLimit)
+	}
+}
+
+func TestTooManyRequests(t *testing.T) {
+	for _, tt := range []struct {
+		retryAfter string
+		want       time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"soon", 0},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.retryAfter != "" {
+				w.Header().Set("Retry-After", tt.retryAfter)
+			}
+			w.WriteHeader(http.StatusTooManyRequests)
+			io.WriteString(w, "slow down")
+		}))
+		_, err := (&Client{URL: srv.URL}).Update(context.Background(), hostname, nil)
+		srv.Close()
+		var rl *RateLimitedError
+		if !errors.Is(err, ErrRateLimited) || !errors.As(err, &rl) || rl.RetryAfter != tt.want {
+			t.Errorf("Retry-After %q: err = %v, want RetryAfter %v", tt.retryAfter, err, tt.want)
+		}
+		if !IsTransient(err) {
+			t.Errorf("Retry-After %q: not transient", tt.retryAfter)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := http.Header{"Retry-After": {now.Add(90 * time.Second).Format(http.TimeFormat)}}
+	if d := parseRetryAfter(h, now); d != 90*time.Second {
+		t.Errorf("HTTP date: %v, want 1m30s", d)
+	}
+}
+
+func TestRetryAfterDelaysRetry(t *testing.T) {
+	var n int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		if n == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+	c, _ := NewClient(srv.URL, AllowInsecure(), WithRetry(2, fixedBackoff(0)))
+	start := time.Now()
+	if _, err := c.Update(context.Background(), hostname, nil); err != nil || n != 2 {
+		t.Fatalf("got %v after %d requests", err, n)
+	}
+	if d := time.Since(start); d < time.Second {
+		t.Errorf("retried after %v, want at least Retry-After", d)
+	}
+}
@@ -0,0 +1,138 @@
+package dyndns
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/url"
+	"time"
+)
+
+// A Backoff decides how long to wait before retrying a failed update.
+// Attempt is the number of attempts that have failed so far, starting at 1.
+type Backoff interface {
+	NextDelay(attempt int) time.Duration
+}
+
+// ExponentialBackoff is a Backoff with full jitter: the delay before
+// retry n is chosen uniformly from [0, min(Max, Base*2^(n-1))].
+// If Max is zero or negative, delays are not capped.
+type ExponentialBackoff struct {
+	Base, Max time.Duration
+}
+
+// DefaultBackoff is the Backoff used by WithRetry when none is given.
+var DefaultBackoff = &ExponentialBackoff{Base: time.Second, Max: 5 * time.Minute}
+
+// NextDelay satisfies the Backoff interface.
+func (b *ExponentialBackoff) NextDelay(attempt int) time.Duration {
+	d := b.Base
+	for i := 1; i < attempt && d < math.MaxInt64/2 && (b.Max <= 0 || d < b.Max); i++ {
+		d *= 2
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// IsTransient reports whether err is a temporary failure worth retrying:
+// a server-side return code such as 911, a 5xx status, an HTTP 429 rate
+// limit, or a network failure such as a timeout, a refused or reset
+// connection, a failed DNS lookup or a response cut short. Account and
+// hostname errors, certificate errors, canceled contexts and other
+// errors from the HTTP client, such as those of WithRoundTripper
+// wrappers, are not transient.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrCertPinMismatch) {
+		return false
+	}
+	if errors.Is(err, Err911) || errors.Is(err, ErrDns) || errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode >= 500
+	}
+	if isCertError(err) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	if errors.As(err, &dnsErr) || errors.As(err, &opErr) {
+		return true
+	}
+	// url.Error is itself a net.Error, so look at the error it wraps.
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		err = ue.Err
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// isCertError reports whether err is a failure to verify a certificate.
+func isCertError(err error) bool {
+	var verr *tls.CertificateVerificationError
+	var unknown x509.UnknownAuthorityError
+	var host x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	return errors.As(err, &verr) || errors.As(err, &unknown) ||
+		errors.As(err, &host) || errors.As(err, &invalid)
+}
+
+// fatalErrors are the return codes that no retry can fix: the account or
+// hostname must be changed first.
+var fatalErrors = []error{
+	ErrAuth, ErrDonator, ErrDomain, ErrNoHost, ErrNumHost, ErrAbuse,
+	ErrAgent, ErrNotYours, ErrNotActive,
+}
+
+// IsFatal reports whether err is a return code that will recur until the
+// account or hostname configuration is fixed, such as badauth, nohost or
+// !yours. Clients should stop updating the hostname after a fatal error.
+func IsFatal(err error) bool {
+	for _, fatal := range fatalErrors {
+		if errors.Is(err, fatal) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithRetry makes Update retry transient failures, making at most attempts
+// requests in total and waiting between them as directed by b.
+// If b is nil, DefaultBackoff is used.
+func WithRetry(attempts int, b Backoff) Option {
+	return func(c *Client) error {
+		if b == nil {
+			b = DefaultBackoff
+		}
+		c.attempts, c.backoff = attempts, b
+		return nil
+	}
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,154 @@
+package dyndns
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	b := &ExponentialBackoff{Base: 100 * time.Millisecond, Max: time.Second}
+	for attempt, max := range map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		3:  400 * time.Millisecond,
+		4:  800 * time.Millisecond,
+		5:  time.Second,
+		50: time.Second,
+	} {
+		for i := 0; i < 100; i++ {
+			if d := b.NextDelay(attempt); d < 0 || d > max {
+				t.Fatalf("NextDelay(%d) = %v, want in [0, %v]", attempt, d, max)
+			}
+		}
+	}
+	if d := (&ExponentialBackoff{}).NextDelay(3); d != 0 {
+		t.Errorf("zero backoff delay = %v", d)
+	}
+
+	// Without Max, delays keep growing.
+	uncapped := &ExponentialBackoff{Base: time.Second}
+	var longest time.Duration
+	for i := 0; i < 100; i++ {
+		d := uncapped.NextDelay(10)
+		if d < 0 || d > 512*time.Second {
+			t.Fatalf("uncapped NextDelay(10) = %v, want in [0, 512s]", d)
+		}
+		longest = max(longest, d)
+	}
+	if longest <= time.Second {
+		t.Errorf("uncapped NextDelay(10) at most %v in 100 tries", longest)
+	}
+	if d := uncapped.NextDelay(1000); d < 0 {
+		t.Errorf("NextDelay(1000) = %v", d)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	for err, want := range map[error]bool{
+		nil:                         false,
+		Err911:                      true,
+		ErrDns:                      true,
+		&ResponseError{Err911, "x"}: true,
+		ErrAuth:                     false,
+		ErrAbuse:                    false,
+		context.Canceled:            false,
+		errors.New("other"):         false,
+
+		urlError(&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}):                  true,
+		urlError(&net.OpError{Op: "read", Err: syscall.ECONNRESET}):                    true,
+		urlError(&net.DNSError{Err: "server misbehaving", Name: "members.dyndns"}):     true,
+		urlError(io.ErrUnexpectedEOF):                                                  true,
+		urlError(io.EOF):                                                               true,
+		urlError(timeoutError{}):                                                       true,
+		urlError(&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}): false,
+		urlError(x509.HostnameError{Host: "members.dyndns.org"}):                       false,
+		urlError(errors.New("wrapper failed")):                                         false,
+		urlError(errors.New("stopped after 10 redirects")):                             false,
+	} {
+		if got := IsTransient(err); got != want {
+			t.Errorf("IsTransient(%v) = %t, want %t", err, got, want)
+		}
+	}
+}
+
+// urlError wraps err as http.Client.Do does.
+func urlError(err error) error {
+	return &url.Error{Op: "Get", URL: "https://members.dyndns.org/nic/update", Err: err}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsFatal(t *testing.T) {
+	for err, want := range map[error]bool{
+		nil:                            false,
+		ErrAuth:                        true,
+		ErrNotYours:                    true,
+		ErrNotActive:                   true,
+		&ResponseError{ErrNoHost, "x"}: true,
+		Err911:                         false,
+		ErrSystem:                      false,
+		context.Canceled:               false,
+	} {
+		if got := IsFatal(err); got != want {
+			t.Errorf("IsFatal(%v) = %t, want %t", err, got, want)
+		}
+	}
+}
+
+type fixedBackoff time.Duration
+
+func (b fixedBackoff) NextDelay(int) time.Duration { return time.Duration(b) }
+
+func TestWithRetry(t *testing.T) {
+	for _, tt := range []struct {
+		attempts, failures, requests int
+		err                          error
+	}{
+		{1, 1, 1, Err911},
+		{3, 2, 3, nil},
+		{3, 5, 3, Err911},
+	} {
+		var n int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n++
+			if n <= tt.failures {
+				io.WriteString(w, "911")
+			} else {
+				io.WriteString(w, "good 1.2.3.4")
+			}
+		}))
+		c, err := NewClient(srv.URL, AllowInsecure(), WithRetry(tt.attempts, fixedBackoff(time.Millisecond)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.Update(context.Background(), hostname, nil)
+		if err != tt.err || n != tt.requests {
+			t.Errorf("attempts %d, failures %d: got %v after %d requests, want %v after %d",
+				tt.attempts, tt.failures, err, n, tt.err, tt.requests)
+		}
+		srv.Close()
+	}
+}
+
+func TestWithRetryNotTransient(t *testing.T) {
+	var n int
+	srv := newTestServer(t, "badauth", &n, nil)
+	c, _ := NewClient(srv.URL, AllowInsecure(), WithRetry(5, fixedBackoff(0)))
+	if _, err := c.Update(context.Background(), hostname, nil); err != ErrAuth || n != 1 {
+		t.Errorf("got %v after %d requests, want badauth after 1", err, n)
+	}
+}
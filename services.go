@@ -1,8 +1,68 @@
 package dyndns
 
+import "fmt"
+
 // Popular dynamic DNS service URLs.
 const (
 	DNS_O_Matic = "https://update.dnsomatic.com/nic/update"
 	DynDNS      = "https://members.dyndns.org/nic/update"
 	No_IP       = "https://dynupdate.no-ip.com/nic/update"
 )
+
+// ProviderInfo describes a dynamic DNS service preset.
+type ProviderInfo struct {
+	Name   string   // short name accepted by NewProvider
+	URL    string   // update URL
+	Auth   string   // how the account authenticates
+	Quirks []string // notable requirements and behaviors
+}
+
+var providers = []ProviderInfo{
+	{
+		Name: "dnsomatic",
+		URL:  DNS_O_Matic,
+		Auth: "HTTP Basic with the OpenDNS account",
+		Quirks: []string{
+			"the hostname all.dnsomatic.com updates every configured service",
+		},
+	},
+	{
+		Name: "dyndns",
+		URL:  DynDNS,
+		Auth: "HTTP Basic with the username and password or an updater client key",
+		Quirks: []string{
+			"requires a descriptive User-Agent",
+			"repeated updates that change nothing are treated as abuse",
+		},
+	},
+	{
+		Name: "noip",
+		URL:  No_IP,
+		Auth: "HTTP Basic with the account email or a DDNS key",
+		Quirks: []string{
+			"requires a User-Agent naming the program, its version and a contact email",
+			"free hostnames expire unless confirmed monthly",
+		},
+	},
+}
+
+// Providers lists the known service presets, sorted by name.
+func Providers() []ProviderInfo {
+	list := make([]ProviderInfo, len(providers))
+	for i, p := range providers {
+		p.Quirks = append([]string(nil), p.Quirks...)
+		list[i] = p
+	}
+	return list
+}
+
+// NewProvider returns a Client for the preset with the given name,
+// as listed by Providers, configured with opts.
+func NewProvider(name string, opts ...Option) (*Client, error) {
+	for _, p := range providers {
+		if p.Name == name {
+			return NewClient(p.URL, opts...)
+		}
+	}
+	return nil, fmt.Errorf("dyndns: unknown provider %q", name)
+}
@@ -0,0 +1,46 @@
+package dyndns
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestProviders(t *testing.T) {
+	list := Providers()
+	want := map[string]string{
+		"dnsomatic": DNS_O_Matic,
+		"dyndns":    DynDNS,
+		"noip":      No_IP,
+	}
+	if len(list) != len(want) {
+		t.Errorf("%d providers, want %d", len(list), len(want))
+	}
+	if !sort.SliceIsSorted(list, func(i, j int) bool { return list[i].Name < list[j].Name }) {
+		t.Error("providers not sorted by name")
+	}
+	for _, p := range list {
+		if want[p.Name] != p.URL {
+			t.Errorf("%s: URL %q, want %q", p.Name, p.URL, want[p.Name])
+		}
+		if p.Auth == "" || len(p.Quirks) == 0 {
+			t.Errorf("%s: missing auth style or quirks", p.Name)
+		}
+	}
+	list[0].Quirks[0] = "changed"
+	if Providers()[0].Quirks[0] == "changed" {
+		t.Error("Providers shares its slices with the registry")
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	c, err := NewProvider("noip", WithCredentials(StaticCredentials(username, password)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.URL != No_IP || c.Credentials == nil {
+		t.Errorf("URL %q, credentials %v", c.URL, c.Credentials)
+	}
+	if _, err := NewProvider("nosuch"); err == nil {
+		t.Error("unknown provider accepted")
+	}
+}
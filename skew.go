@@ -0,0 +1,46 @@
+package dyndns
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultMaxClockSkew is the default for Client.MaxClockSkew.
+const DefaultMaxClockSkew = 5 * time.Minute
+
+// A ClockSkewError is a warning that the local clock differs from the
+// server's Date header by more than the Client's MaxClockSkew. Providers
+// with time-based tokens may then reject the credentials.
+type ClockSkewError struct {
+	Skew time.Duration // local time minus server time
+}
+
+// Error satisfies the built-in error interface.
+func (e *ClockSkewError) Error() string {
+	dir, skew := "ahead of", e.Skew
+	if skew < 0 {
+		dir, skew = "behind", -skew
+	}
+	return fmt.Sprintf("dyndns: local clock is %v %s the server's; check NTP", skew.Round(time.Second), dir)
+}
+
+// clockSkew returns a ClockSkewError if the Date in h is further from now
+// than allowed, or nil.
+func (c *Client) clockSkew(h http.Header, now time.Time) error {
+	max := c.MaxClockSkew
+	if max == 0 {
+		max = DefaultMaxClockSkew
+	}
+	if max < 0 {
+		return nil
+	}
+	date, err := http.ParseTime(h.Get("Date"))
+	if err != nil {
+		return nil
+	}
+	if skew := now.Sub(date); skew > max || skew < -max {
+		return &ClockSkewError{skew}
+	}
+	return nil
+}
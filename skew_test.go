@@ -0,0 +1,50 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClockSkewWarning(t *testing.T) {
+	var date time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", date.UTC().Format(http.TimeFormat))
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+	c := &Client{URL: srv.URL}
+	ctx := context.Background()
+
+	date = time.Now().Add(-time.Hour)
+	res, err := c.Update(ctx, hostname, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cs *ClockSkewError
+	if len(res.Warnings) != 1 || !errors.As(res.Warnings[0], &cs) {
+		t.Fatalf("warnings = %v, want ClockSkewError", res.Warnings)
+	}
+	if cs.Skew < 59*time.Minute || cs.Skew > 61*time.Minute {
+		t.Errorf("skew = %v, want about 1h", cs.Skew)
+	}
+	behind := &ClockSkewError{-90 * time.Second}
+	if want := "dyndns: local clock is 1m30s behind the server's; check NTP"; behind.Error() != want {
+		t.Errorf("message %q, want %q", behind, want)
+	}
+
+	date = time.Now()
+	if res, _ := c.Update(ctx, hostname, nil); len(res.Warnings) != 0 {
+		t.Errorf("in sync: warnings = %v", res.Warnings)
+	}
+
+	date = time.Now().Add(time.Hour)
+	c.MaxClockSkew = -1
+	if res, _ := c.Update(ctx, hostname, nil); len(res.Warnings) != 0 {
+		t.Errorf("check disabled: warnings = %v", res.Warnings)
+	}
+}
@@ -0,0 +1,115 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// An IPSource finds the address to publish. CurrentIP returns an address
+// of the given family, 4 or 6, or of either family if family is 0.
+type IPSource interface {
+	CurrentIP(ctx context.Context, family int) (net.IP, error)
+}
+
+// The IPSourceFunc type is an adapter to allow the use of ordinary
+// functions as IPSources.
+type IPSourceFunc func(ctx context.Context, family int) (net.IP, error)
+
+// CurrentIP returns f(ctx, family).
+func (f IPSourceFunc) CurrentIP(ctx context.Context, family int) (net.IP, error) {
+	return f(ctx, family)
+}
+
+// checkFamily returns ip if it is of the given family, as for CurrentIP.
+func checkFamily(ip net.IP, family int) (net.IP, error) {
+	if family != 0 && ipFamily(ip) != family {
+		return nil, fmt.Errorf("dyndns: %v is not an IPv%d address", ip, family)
+	}
+	return ip, nil
+}
+
+// StaticSource returns a source that always reports ip.
+func StaticSource(ip net.IP) IPSource {
+	return IPSourceFunc(func(_ context.Context, family int) (net.IP, error) {
+		return checkFamily(ip, family)
+	})
+}
+
+// CheckIPSource returns a source that asks c's CheckIPURL service, with
+// DetectIP for either family and DetectIPFamily for a given one.
+func CheckIPSource(c *Client) IPSource {
+	return IPSourceFunc(func(ctx context.Context, family int) (net.IP, error) {
+		if family == 0 {
+			return c.DetectIP(ctx)
+		}
+		return c.DetectIPFamily(ctx, family)
+	})
+}
+
+// DNSSource returns a source that uses DetectIPViaDNS with resolver.
+// The family of the address depends on how resolver reaches the server.
+func DNSSource(resolver *net.Resolver) IPSource {
+	return IPSourceFunc(func(ctx context.Context, family int) (net.IP, error) {
+		ip, err := DetectIPViaDNS(ctx, resolver)
+		if err != nil {
+			return nil, err
+		}
+		return checkFamily(ip, family)
+	})
+}
+
+// InterfaceSource returns a source that reports the first global address
+// of the requested family on the named network interfaces, as used by
+// UpdateFromInterfaces.
+func InterfaceSource(names ...string) IPSource {
+	return interfaceSource(names, interfaceAddrs)
+}
+
+func interfaceSource(names []string, lookup func(string) ([]net.Addr, error)) IPSource {
+	return IPSourceFunc(func(_ context.Context, family int) (net.IP, error) {
+		ips, err := globalAddrs(names, lookup)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			if family == 0 || ipFamily(ip) == family {
+				return ip, nil
+			}
+		}
+		return nil, fmt.Errorf("dyndns: no global IPv%d address on interfaces %q", family, names)
+	})
+}
+
+// FallbackSource returns a source that tries each of sources in order and
+// reports the first address found. If all fail, the error joins theirs.
+func FallbackSource(sources ...IPSource) IPSource {
+	return IPSourceFunc(func(ctx context.Context, family int) (net.IP, error) {
+		var errs []error
+		for _, s := range sources {
+			ip, err := s.CurrentIP(ctx, family)
+			if err == nil {
+				return ip, nil
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			errs = append(errs, err)
+		}
+		if len(errs) == 0 {
+			return nil, errors.New("dyndns: no address sources")
+		}
+		return nil, errors.Join(errs...)
+	})
+}
+
+// UpdateFromSource updates hostname to the address of either family
+// reported by src.
+func (c *Client) UpdateFromSource(ctx context.Context, hostname string, src IPSource, opts ...UpdateOption) (UpdateResult, error) {
+	ip, err := src.CurrentIP(ctx, 0)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	return c.Update(ctx, hostname, ip, opts...)
+}
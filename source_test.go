@@ -0,0 +1,76 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestFallbackSource(t *testing.T) {
+	var calls []string
+	source := func(name string, ip net.IP, err error) IPSource {
+		return IPSourceFunc(func(_ context.Context, family int) (net.IP, error) {
+			calls = append(calls, name)
+			if err != nil {
+				return nil, err
+			}
+			return checkFamily(ip, family)
+		})
+	}
+	errA, errB := errors.New("a failed"), errors.New("b failed")
+	ctx := context.Background()
+
+	s := FallbackSource(
+		source("a", nil, errA),
+		source("b", net.IPv4(1, 2, 3, 4), nil),
+		source("c", net.ParseIP("2001:db8::1"), nil),
+	)
+	if ip, err := s.CurrentIP(ctx, 0); err != nil || !ip.Equal(net.IPv4(1, 2, 3, 4)) {
+		t.Errorf("any family: got %v, %v", ip, err)
+	}
+	if !reflect.DeepEqual(calls, []string{"a", "b"}) {
+		t.Errorf("calls = %q, want a, b", calls)
+	}
+
+	calls = nil
+	if ip, err := s.CurrentIP(ctx, 6); err != nil || !ip.Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("IPv6: got %v, %v", ip, err)
+	}
+	if !reflect.DeepEqual(calls, []string{"a", "b", "c"}) {
+		t.Errorf("calls = %q, want a, b, c", calls)
+	}
+
+	_, err := FallbackSource(source("a", nil, errA), source("b", nil, errB)).CurrentIP(ctx, 0)
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("all failed: err = %v", err)
+	}
+	if _, err := FallbackSource().CurrentIP(ctx, 0); err == nil {
+		t.Error("no sources: no error")
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	calls = nil
+	_, err = FallbackSource(source("a", nil, errA), source("b", nil, errB)).CurrentIP(canceled, 0)
+	if err != context.Canceled || len(calls) != 1 {
+		t.Errorf("canceled: err = %v after %q", err, calls)
+	}
+}
+
+func TestInterfaceSource(t *testing.T) {
+	s := interfaceSource([]string{"lan0", "wan0"}, stubInterfaces(map[string][]string{
+		"lan0": {"192.168.1.1/24"},
+		"wan0": {"2001:db8::5/64", "1.2.3.4/24"},
+	}))
+	ctx := context.Background()
+	for family, want := range map[int]string{0: "2001:db8::5", 4: "1.2.3.4", 6: "2001:db8::5"} {
+		if ip, err := s.CurrentIP(ctx, family); err != nil || ip.String() != want {
+			t.Errorf("family %d: got %v, %v; want %s", family, ip, err, want)
+		}
+	}
+	if _, err := StaticSource(net.IPv4(1, 2, 3, 4)).CurrentIP(ctx, 6); err == nil {
+		t.Error("StaticSource returned IPv4 for family 6")
+	}
+}
@@ -0,0 +1,296 @@
+package dyndns
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrHostDisabled is returned by Update for a hostname that the service
+// blocked for abuse. The Client stops sending updates for the hostname
+// until it is re-enabled with Enable or ResetAll, or the Client's
+// AbuseCooldown passes.
+var ErrHostDisabled = errors.New("dyndns: hostname disabled after abuse response")
+
+// hostState is what a Client remembers about a hostname between updates.
+type hostState struct {
+	disabled bool
+	abused   time.Time // start of the current AbuseCooldown
+	updated  time.Time // time of the last successful update
+	ip       net.IP    // address published by the last successful update
+	nochg    bool      // whether the last successful update changed nothing
+}
+
+// stateKey returns the name under which the state of hostname is kept:
+// the form Update sends, or hostname itself if it cannot be normalized.
+func (c *Client) stateKey(hostname string) string {
+	if name, err := c.normalizeHostname(hostname); err == nil {
+		return name
+	}
+	return hostname
+}
+
+// host returns the state for hostname, creating it if needed.
+// c.mu must be held.
+func (c *Client) host(hostname string) *hostState {
+	if c.hosts == nil {
+		c.hosts = make(map[string]*hostState)
+	}
+	h := c.hosts[hostname]
+	if h == nil {
+		h = new(hostState)
+		c.hosts[hostname] = h
+	}
+	return h
+}
+
+// isDisabled reports whether updates of hostname are blocked.
+func (c *Client) isDisabled(hostname string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.hosts[hostname]
+	return h != nil && c.blocked(h, time.Now())
+}
+
+// takeProbes is called right before a request for hostnames, a
+// comma-separated list, is sent. Once the AbuseCooldown of a disabled
+// hostname passes, a single request goes out as a probe and starts a new
+// cooldown; only a successful response re-enables the hostname. It returns
+// ErrHostDisabled if another request took the probe first.
+func (c *Client) takeProbes(hostnames string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	var probes []*hostState
+	for _, name := range strings.Split(hostnames, ",") {
+		h := c.hosts[name]
+		if h == nil || !h.disabled {
+			continue
+		}
+		if c.blocked(h, now) {
+			return ErrHostDisabled
+		}
+		probes = append(probes, h)
+	}
+	for _, h := range probes {
+		h.abused = now
+	}
+	return nil
+}
+
+// blocked reports whether h is disabled at now, taking AbuseCooldown
+// into account.
+func (c *Client) blocked(h *hostState, now time.Time) bool {
+	if !h.disabled {
+		return false
+	}
+	return c.AbuseCooldown <= 0 || now.Before(h.abused.Add(c.AbuseCooldown))
+}
+
+// record updates the state for hostname after a response to an update
+// that sent ip. It returns the previous address and whether it changed.
+func (c *Client) record(hostname string, ip net.IP, res UpdateResult) (old net.IP, changed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.host(hostname)
+	old = h.ip
+	switch {
+	case res.Code == CodeAbuse:
+		h.disabled, h.abused = true, time.Now()
+	case c.isSuccess(res.Code):
+		h.disabled = false
+		h.updated = time.Now()
+		h.nochg = res.Code == CodeNoChange
+		if res.IP != nil {
+			ip = res.IP
+		}
+		if ip != nil && !ip.Equal(h.ip) {
+			h.ip = ip
+			changed = true
+		}
+	}
+	return old, changed
+}
+
+// lastUpdate returns the time and address of the last successful update
+// of hostname, or zero values if there was none.
+func (c *Client) lastUpdate(hostname string) (time.Time, net.IP) {
+	hostname = c.stateKey(hostname)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if h := c.hosts[hostname]; h != nil {
+		return h.updated, h.ip
+	}
+	return time.Time{}, nil
+}
+
+// DefaultMinUpdateInterval is the default for Client.MinUpdateInterval.
+const DefaultMinUpdateInterval = 10 * time.Minute
+
+// NextSafeUpdate returns when hostname can next be updated without risking
+// an abuse block. Providers treat repeated updates that change nothing as
+// abuse, so after a nochg response it is MinUpdateInterval after that
+// update. After an update that changed the address, or if the hostname
+// has not been updated, it is the zero time: updating now is safe.
+func (c *Client) NextSafeUpdate(hostname string) time.Time {
+	hostname = c.stateKey(hostname)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.hosts[hostname]
+	if h == nil || !h.nochg {
+		return time.Time{}
+	}
+	return h.updated.Add(c.minUpdateInterval())
+}
+
+// ShouldUpdate reports whether updating hostname to desired is warranted,
+// from what the Client remembers of previous updates, with a reason:
+//
+//	"IP changed"         desired differs from the last published address
+//	"no previous update" the Client has not updated hostname
+//	"IP unchanged"       desired is already published
+//	"forced"             desired is published, but RefreshInterval has
+//	                     passed, or desired is nil and it is safe to let
+//	                     the service detect the address
+//	"too soon"           desired is nil and NextSafeUpdate is in the future,
+//	                     or RejectTooSoon would refuse the request
+//	"hostname disabled"  the service blocked hostname for abuse
+func (c *Client) ShouldUpdate(hostname string, desired net.IP) (bool, string) {
+	hostname = c.stateKey(hostname)
+	now := time.Now()
+	c.mu.Lock()
+	var h hostState
+	if p := c.hosts[hostname]; p != nil {
+		h = *p
+	}
+	c.mu.Unlock()
+	if c.blocked(&h, now) {
+		return false, "hostname disabled"
+	}
+	if c.MinInterval > 0 && c.rejectTooSoon {
+		c.paceMu.Lock()
+		next := c.nextRequest
+		c.paceMu.Unlock()
+		if now.Before(next) {
+			return false, "too soon"
+		}
+	}
+	switch {
+	case desired == nil:
+		if h.nochg && now.Before(h.updated.Add(c.minUpdateInterval())) {
+			return false, "too soon"
+		}
+		return true, "forced"
+	case h.ip == nil:
+		return true, "no previous update"
+	case !desired.Equal(h.ip):
+		return true, "IP changed"
+	case c.RefreshInterval > 0 && !now.Before(h.updated.Add(c.RefreshInterval)):
+		return true, "forced"
+	}
+	return false, "IP unchanged"
+}
+
+func (c *Client) minUpdateInterval() time.Duration {
+	if c.MinUpdateInterval > 0 {
+		return c.MinUpdateInterval
+	}
+	return DefaultMinUpdateInterval
+}
+
+// A RedundantIPPolicy decides what Update does when the address to send
+// equals the one the server reported for the hostname after the last
+// successful update. Avoiding redundant explicit updates lowers the risk
+// of an abuse block. Updates with a nil ip, and hostnames with no known
+// address or a different one, are always sent unchanged.
+type RedundantIPPolicy int
+
+const (
+	// SendRedundantIP sends the update with myip as usual.
+	SendRedundantIP RedundantIPPolicy = iota
+
+	// OmitRedundantIP sends the update without myip, letting the
+	// server detect the address and reply nochg.
+	OmitRedundantIP
+
+	// SkipRedundantIP sends nothing. Update returns a nochg result
+	// with the known address.
+	SkipRedundantIP
+)
+
+// DisabledHosts returns the sorted hostnames currently blocked from updates.
+func (c *Client) DisabledHosts() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var names []string
+	now := time.Now()
+	for name, h := range c.hosts {
+		if c.blocked(h, now) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Enable allows updates for a hostname disabled after an abuse response.
+func (c *Client) Enable(hostname string) {
+	hostname = c.stateKey(hostname)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if h := c.hosts[hostname]; h != nil {
+		h.disabled = false
+	}
+}
+
+// ResetAll re-enables every disabled hostname.
+func (c *Client) ResetAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, h := range c.hosts {
+		h.disabled = false
+	}
+}
+
+// savedHost is the serialized form of a hostState.
+type savedHost struct {
+	IP       net.IP    `json:"ip,omitempty"`
+	Updated  time.Time `json:"updated"`
+	NoChange bool      `json:"nochg,omitempty"`
+	Disabled bool      `json:"disabled,omitempty"`
+	Abused   time.Time `json:"abused"`
+}
+
+// ExportState returns what the Client remembers about each hostname, such
+// as the last published address, the time of the last successful update
+// and whether the hostname is disabled, as JSON for ImportState.
+func (c *Client) ExportState() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	saved := make(map[string]savedHost, len(c.hosts))
+	for name, h := range c.hosts {
+		saved[name] = savedHost{IP: h.ip, Updated: h.updated, NoChange: h.nochg, Disabled: h.disabled, Abused: h.abused}
+	}
+	return json.Marshal(saved)
+}
+
+// ImportState replaces the Client's per-hostname state with data
+// returned by ExportState, for example by another process.
+func (c *Client) ImportState(data []byte) error {
+	var saved map[string]savedHost
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("dyndns: import state: %w", err)
+	}
+	hosts := make(map[string]*hostState, len(saved))
+	for name, s := range saved {
+		hosts[c.stateKey(name)] = &hostState{disabled: s.Disabled, abused: s.Abused, updated: s.Updated, ip: s.IP, nochg: s.NoChange}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hosts = hosts
+	return nil
+}
@@ -0,0 +1,436 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDisableOnAbuse(t *testing.T) {
+	var n int
+	srv := newTestServer(t, "abuse", &n, nil)
+	c := &Client{URL: srv.URL}
+	ctx := context.Background()
+	if _, err := c.Update(ctx, hostname, nil); !errors.Is(err, ErrAbuse) {
+		t.Fatalf("err = %v, want ErrAbuse", err)
+	}
+	if _, err := c.Update(ctx, hostname, nil); err != ErrHostDisabled {
+		t.Fatalf("err = %v, want ErrHostDisabled", err)
+	}
+	if n != 1 {
+		t.Errorf("server saw %d requests, want 1", n)
+	}
+	c.Update(ctx, "other.dyndns.org", nil)
+	if got, want := c.DisabledHosts(), []string{"other.dyndns.org", hostname}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DisabledHosts() = %q, want %q", got, want)
+	}
+
+	c.Enable(hostname)
+	if got := c.DisabledHosts(); !reflect.DeepEqual(got, []string{"other.dyndns.org"}) {
+		t.Errorf("after Enable, DisabledHosts() = %q", got)
+	}
+	if _, err := c.Update(ctx, hostname, nil); !errors.Is(err, ErrAbuse) {
+		t.Errorf("after Enable, err = %v, want ErrAbuse", err)
+	}
+
+	c.ResetAll()
+	if got := c.DisabledHosts(); len(got) != 0 {
+		t.Errorf("after ResetAll, DisabledHosts() = %q", got)
+	}
+}
+
+func TestStateMixedCase(t *testing.T) {
+	var n int
+	srv := newTestServer(t, "abuse", &n, nil)
+	c := &Client{URL: srv.URL}
+	ctx := context.Background()
+	c.Update(ctx, "Test.DynDNS.org", nil)
+	if ok, reason := c.ShouldUpdate("TEST.dyndns.org", nil); ok || reason != "hostname disabled" {
+		t.Errorf("ShouldUpdate = %v, %q", ok, reason)
+	}
+	c.Enable("Test.DynDNS.org")
+	if got := c.DisabledHosts(); len(got) != 0 {
+		t.Errorf("after Enable, DisabledHosts() = %q", got)
+	}
+
+	if err := c.ImportState([]byte(`{"Other.DynDNS.org":{"disabled":true}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Update(ctx, "other.dyndns.org", nil); err != ErrHostDisabled {
+		t.Errorf("after ImportState, err = %v, want ErrHostDisabled", err)
+	}
+}
+
+func TestAbuseCooldown(t *testing.T) {
+	var n int
+	body := "abuse"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+	c := &Client{URL: srv.URL, AbuseCooldown: time.Hour}
+	ctx := context.Background()
+	endCooldown := func(ago time.Duration) {
+		c.mu.Lock()
+		c.host(hostname).abused = time.Now().Add(-ago)
+		c.mu.Unlock()
+	}
+	if _, err := c.Update(ctx, hostname, nil); !errors.Is(err, ErrAbuse) {
+		t.Fatalf("err = %v, want ErrAbuse", err)
+	}
+	endCooldown(59 * time.Minute)
+	if _, err := c.Update(ctx, hostname, nil); err != ErrHostDisabled {
+		t.Fatalf("during cooldown, err = %v, want ErrHostDisabled", err)
+	}
+	if ok, reason := c.ShouldUpdate(hostname, nil); ok || reason != "hostname disabled" {
+		t.Errorf("during cooldown, ShouldUpdate = %v, %q", ok, reason)
+	}
+	if n != 1 {
+		t.Fatalf("server saw %d requests during cooldown, want 1", n)
+	}
+
+	// After the cooldown, a single update goes out, whatever the response.
+	endCooldown(time.Hour)
+	if got := c.DisabledHosts(); len(got) != 0 {
+		t.Errorf("after cooldown, DisabledHosts() = %q", got)
+	}
+	if ok, _ := c.ShouldUpdate(hostname, nil); !ok {
+		t.Error("after cooldown, ShouldUpdate = false")
+	}
+	body = "911"
+	for i := 0; i < 3; i++ {
+		c.Update(ctx, hostname, nil)
+	}
+	if n != 2 {
+		t.Fatalf("server saw %d requests after the first cooldown, want 2", n)
+	}
+
+	body = "abuse"
+	endCooldown(time.Hour)
+	if _, err := c.Update(ctx, hostname, nil); !errors.Is(err, ErrAbuse) {
+		t.Fatalf("after cooldown, err = %v, want ErrAbuse", err)
+	}
+	if _, err := c.Update(ctx, hostname, nil); err != ErrHostDisabled {
+		t.Errorf("after second abuse, err = %v, want ErrHostDisabled", err)
+	}
+	if n != 3 {
+		t.Fatalf("server saw %d requests after the second cooldown, want 3", n)
+	}
+
+	// A successful probe re-enables the hostname.
+	body = "good 1.2.3.4"
+	endCooldown(time.Hour)
+	for i := 0; i < 2; i++ {
+		if _, err := c.Update(ctx, hostname, nil); err != nil {
+			t.Fatalf("after successful probe: %v", err)
+		}
+	}
+	if n != 5 {
+		t.Errorf("server saw %d requests, want 5", n)
+	}
+}
+
+// Updates that return before a request is sent leave the probe for the
+// next one.
+func TestAbuseCooldownEarlyReturns(t *testing.T) {
+	var n int
+	srv := newTestServer(t, "good 1.2.3.4", &n, nil)
+	c, err := NewClient(srv.URL, AllowInsecure(), RejectTooSoon(),
+		WithRedundantIPPolicy(SkipRedundantIP), WithCircuitBreaker(1, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.AbuseCooldown = time.Hour
+	ip := net.IPv4(1, 2, 3, 4)
+	c.mu.Lock()
+	h := c.host(hostname)
+	h.ip, h.updated = ip, time.Now().Add(-2*time.Hour)
+	h.disabled, h.abused = true, time.Now().Add(-time.Hour)
+	c.mu.Unlock()
+	ctx := context.Background()
+
+	bad := func(*updateParams) error { return errors.New("bad option") }
+	if _, err := c.Update(ctx, hostname, nil, bad); err == nil {
+		t.Error("invalid option: err = nil")
+	}
+	if res, err := c.Update(ctx, hostname, ip); err != nil || res.Code != CodeNoChange {
+		t.Errorf("redundant address: got %+v, %v; want nochg", res, err)
+	}
+	c.paceMu.Lock()
+	c.nextRequest = time.Now().Add(time.Hour)
+	c.paceMu.Unlock()
+	c.MinInterval = time.Hour
+	if _, err := c.Update(ctx, hostname, nil); err != ErrTooSoon {
+		t.Errorf("err = %v, want ErrTooSoon", err)
+	}
+	c.MinInterval = 0
+	c.breaker.state, c.breaker.opened = CircuitOpen, time.Now()
+	if _, err := c.Update(ctx, hostname, nil); err != ErrCircuitOpen {
+		t.Errorf("err = %v, want ErrCircuitOpen", err)
+	}
+	c.breaker.state = CircuitClosed
+	if n != 0 {
+		t.Fatalf("server saw %d requests, want 0", n)
+	}
+	if _, err := c.Update(ctx, hostname, nil); err != nil {
+		t.Errorf("probe: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("server saw %d requests, want 1", n)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	var ims []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := r.Header.Get("If-Modified-Since")
+		ims = append(ims, v)
+		if v != "" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+	c, err := NewClient(srv.URL, AllowInsecure(), WithConditionalRequests())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := c.Update(ctx, hostname, nil); err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Update(ctx, hostname, nil)
+	if err != nil || res.Code != CodeNoChange || !res.IP.Equal(net.IPv4(1, 2, 3, 4)) {
+		t.Errorf("after 304: got %+v, %v; want nochg 1.2.3.4", res, err)
+	}
+	if len(ims) != 2 || ims[0] != "" {
+		t.Fatalf("If-Modified-Since headers = %q", ims)
+	}
+	if _, err := http.ParseTime(ims[1]); err != nil {
+		t.Errorf("If-Modified-Since = %q: %v", ims[1], err)
+	}
+}
+
+func TestConditionalRequestsNewAddress(t *testing.T) {
+	var ims []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ims = append(ims, r.Header.Get("If-Modified-Since"))
+		if ims[len(ims)-1] != "" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, "good "+r.URL.Query().Get("myip"))
+	}))
+	defer srv.Close()
+	c, err := NewClient(srv.URL, AllowInsecure(), WithConditionalRequests())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := c.Update(ctx, hostname, net.IPv4(1, 2, 3, 4)); err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Update(ctx, hostname, net.IPv4(5, 6, 7, 8))
+	if err != nil || res.Code != CodeGood || !res.IP.Equal(net.IPv4(5, 6, 7, 8)) {
+		t.Errorf("new address: got %+v, %v; want good 5.6.7.8", res, err)
+	}
+	if _, ip := c.lastUpdate(hostname); !ip.Equal(net.IPv4(5, 6, 7, 8)) {
+		t.Errorf("recorded %v, want 5.6.7.8", ip)
+	}
+	if _, err := c.Update(ctx, hostname, net.IPv4(5, 6, 7, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if len(ims) != 3 || ims[0] != "" || ims[1] != "" || ims[2] == "" {
+		t.Errorf("If-Modified-Since headers = %q, want only on the unchanged update", ims)
+	}
+}
+
+func TestConditionalRequestsOff(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Modified-Since") != "" {
+			t.Error("If-Modified-Since sent without WithConditionalRequests")
+		}
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+	c, _ := NewClient(srv.URL, AllowInsecure())
+	c.Update(context.Background(), hostname, nil)
+	c.Update(context.Background(), hostname, nil)
+}
+
+func TestNextSafeUpdate(t *testing.T) {
+	c := &Client{URL: newTestClient(t, "nochg 1.2.3.4").URL, MinUpdateInterval: time.Hour}
+	if next := c.NextSafeUpdate(hostname); !next.IsZero() {
+		t.Errorf("before any update, NextSafeUpdate = %v", next)
+	}
+	start := time.Now()
+	if _, err := c.Update(context.Background(), hostname, nil); err != nil {
+		t.Fatal(err)
+	}
+	next := c.NextSafeUpdate(hostname)
+	if next.Before(start.Add(time.Hour)) || next.After(time.Now().Add(time.Hour)) {
+		t.Errorf("after nochg, NextSafeUpdate = %v, want an hour from now", next)
+	}
+
+	c.URL = newTestClient(t, "good 5.6.7.8").URL
+	if _, err := c.Update(context.Background(), hostname, nil); err != nil {
+		t.Fatal(err)
+	}
+	if next := c.NextSafeUpdate(hostname); !next.IsZero() {
+		t.Errorf("after good, NextSafeUpdate = %v", next)
+	}
+}
+
+func TestNextSafeUpdateDefault(t *testing.T) {
+	c := &Client{}
+	c.host(hostname).updated = time.Unix(1000, 0)
+	c.host(hostname).nochg = true
+	if got, want := c.NextSafeUpdate(hostname), time.Unix(1000, 0).Add(DefaultMinUpdateInterval); !got.Equal(want) {
+		t.Errorf("NextSafeUpdate = %v, want %v", got, want)
+	}
+}
+
+func TestRedundantIPPolicy(t *testing.T) {
+	known, other := net.IPv4(1, 2, 3, 4), net.IPv4(5, 6, 7, 8)
+	for _, tt := range []struct {
+		policy RedundantIPPolicy
+		ip     net.IP
+		sent   bool
+		myip   string
+	}{
+		{SendRedundantIP, known, true, "1.2.3.4"},
+		{OmitRedundantIP, known, true, ""},
+		{OmitRedundantIP, other, true, "5.6.7.8"},
+		{OmitRedundantIP, nil, true, ""},
+		{SkipRedundantIP, known, false, ""},
+		{SkipRedundantIP, other, true, "5.6.7.8"},
+	} {
+		var q url.Values
+		c := &Client{URL: newTestServer(t, "good 1.2.3.4", nil, &q).URL}
+		c.redundantIP = tt.policy
+		ctx := context.Background()
+		if _, err := c.Update(ctx, hostname, known); err != nil {
+			t.Fatal(err)
+		}
+		q = nil
+		res, err := c.Update(ctx, hostname, tt.ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sent := q != nil; sent != tt.sent || sent && q.Get("myip") != tt.myip {
+			t.Errorf("policy %d, ip %v: sent %t with myip %q; want %t, %q",
+				tt.policy, tt.ip, sent, q.Get("myip"), tt.sent, tt.myip)
+		}
+		if !tt.sent && (res.Code != CodeNoChange || !res.IP.Equal(known)) {
+			t.Errorf("policy %d, ip %v: skipped result = %+v", tt.policy, tt.ip, res)
+		}
+	}
+}
+
+func TestExportImportState(t *testing.T) {
+	var n int
+	srv := newTestServer(t, "nochg 1.2.3.4", &n, nil)
+	c := &Client{URL: srv.URL}
+	ctx := context.Background()
+	c.Update(ctx, hostname, nil)
+	c.mu.Lock()
+	c.host("other.dyndns.org").disabled = true
+	c.mu.Unlock()
+
+	data, err := c.ExportState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fresh Client
+	if err := fresh.ImportState(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := fresh.DisabledHosts(); !reflect.DeepEqual(got, []string{"other.dyndns.org"}) {
+		t.Errorf("DisabledHosts() = %q", got)
+	}
+	updated, ip := c.lastUpdate(hostname)
+	gotUpdated, gotIP := fresh.lastUpdate(hostname)
+	if !gotUpdated.Equal(updated) || !gotIP.Equal(ip) {
+		t.Errorf("lastUpdate = %v, %v; want %v, %v", gotUpdated, gotIP, updated, ip)
+	}
+	if got, want := fresh.NextSafeUpdate(hostname), c.NextSafeUpdate(hostname); !got.Equal(want) {
+		t.Errorf("NextSafeUpdate = %v, want %v", got, want)
+	}
+
+	if err := fresh.ImportState([]byte("not json")); err == nil {
+		t.Error("invalid state accepted")
+	}
+}
+
+func TestOnChange(t *testing.T) {
+	body := "good 1.2.3.4"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+	var changes []string
+	c := &Client{URL: srv.URL, OnChange: func(hostname string, old, new net.IP) {
+		changes = append(changes, hostname+" "+old.String()+" "+new.String())
+	}}
+	ctx := context.Background()
+	c.Update(ctx, hostname, nil)
+	body = "nochg 1.2.3.4"
+	c.Update(ctx, hostname, nil)
+	body = "good 5.6.7.8"
+	c.Update(ctx, hostname, nil)
+	body = "badauth"
+	c.Update(ctx, hostname, nil)
+
+	want := []string{hostname + " <nil> 1.2.3.4", hostname + " 1.2.3.4 5.6.7.8"}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %q, want %q", changes, want)
+	}
+}
+
+func TestShouldUpdate(t *testing.T) {
+	c := &Client{MinUpdateInterval: time.Hour}
+	set := func(h hostState) {
+		c.mu.Lock()
+		*c.host(hostname) = h
+		c.mu.Unlock()
+	}
+	ip, other := net.IPv4(1, 2, 3, 4), net.IPv4(5, 6, 7, 8)
+	check := func(desired net.IP, wantOK bool, wantReason string) {
+		t.Helper()
+		if ok, reason := c.ShouldUpdate(hostname, desired); ok != wantOK || reason != wantReason {
+			t.Errorf("ShouldUpdate(%v) = %t, %q; want %t, %q", desired, ok, reason, wantOK, wantReason)
+		}
+	}
+
+	check(ip, true, "no previous update")
+	check(nil, true, "forced")
+
+	set(hostState{ip: ip, updated: time.Now()})
+	check(ip, false, "IP unchanged")
+	check(other, true, "IP changed")
+
+	c.RefreshInterval = time.Minute
+	check(ip, false, "IP unchanged")
+	set(hostState{ip: ip, updated: time.Now().Add(-2 * time.Minute)})
+	check(ip, true, "forced")
+
+	set(hostState{ip: ip, updated: time.Now(), nochg: true})
+	check(nil, false, "too soon")
+
+	set(hostState{ip: ip, updated: time.Now()})
+	c.MinInterval, c.rejectTooSoon, c.nextRequest = time.Hour, true, time.Now().Add(time.Hour)
+	check(other, false, "too soon")
+	c.MinInterval = 0
+
+	set(hostState{ip: ip, disabled: true})
+	check(other, false, "hostname disabled")
+}
@@ -0,0 +1,102 @@
+package dyndns
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Stats counts a Client's update requests and results.
+type Stats struct {
+	Requests int64          // update requests attempted
+	Errors   int64          // requests that got no protocol response
+	Codes    map[Code]int64 // results by return code, or "invalid" for unknown codes
+}
+
+// codeInvalid counts the results whose code is neither registered nor one
+// of the Client's success codes, so that a misbehaving server cannot
+// create an unbounded number of counters.
+const codeInvalid Code = "invalid"
+
+// statsCode returns the code under which a result with code is counted.
+func (c *Client) statsCode(code Code) Code {
+	if code.known() || c.successCodes[code] {
+		return code
+	}
+	return codeInvalid
+}
+
+// stats holds a Client's counters.
+type stats struct {
+	mu       sync.Mutex
+	requests int64
+	errors   int64
+	codes    map[Code]int64
+}
+
+// request counts an update request that failed with err, or got
+// a response if err is nil.
+func (s *stats) request(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	if err != nil {
+		s.errors++
+	}
+}
+
+// result counts a result with code for one hostname.
+func (s *stats) result(code Code) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.codes == nil {
+		s.codes = make(map[Code]int64)
+	}
+	s.codes[code]++
+}
+
+// Stats returns a snapshot of the Client's counters.
+func (c *Client) Stats() Stats {
+	c.counters.mu.Lock()
+	defer c.counters.mu.Unlock()
+	st := Stats{Requests: c.counters.requests, Errors: c.counters.errors, Codes: make(map[Code]int64, len(c.counters.codes))}
+	for code, n := range c.counters.codes {
+		st.Codes[code] = n
+	}
+	return st
+}
+
+// WriteMetrics writes the Client's counters to w in the Prometheus text
+// exposition format, for serving on a /metrics endpoint:
+//
+//	dyndns_requests_total       update requests attempted
+//	dyndns_request_errors_total requests that got no protocol response
+//	dyndns_updates_total{code}  results by return code, with code="invalid"
+//	                            for codes the Client does not know
+func (c *Client) WriteMetrics(w io.Writer) error {
+	st := c.Stats()
+	bw := bufio.NewWriter(w)
+	metric := func(name, help string) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	}
+	metric("dyndns_requests_total", "Update requests attempted.")
+	fmt.Fprintf(bw, "dyndns_requests_total %d\n", st.Requests)
+	metric("dyndns_request_errors_total", "Update requests that got no protocol response.")
+	fmt.Fprintf(bw, "dyndns_request_errors_total %d\n", st.Errors)
+	metric("dyndns_updates_total", "Update results by return code.")
+	codes := make([]string, 0, len(st.Codes))
+	for code := range st.Codes {
+		codes = append(codes, string(code))
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(bw, "dyndns_updates_total{code=\"%s\"} %d\n", labelEscaper.Replace(code), st.Codes[Code(code)])
+	}
+	return bw.Flush()
+}
+
+// labelEscaper escapes label values in the text exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,57 @@
+package dyndns
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, "good 1.2.3.4")
+	c.Update(ctx, hostname, nil)
+	c.Update(ctx, hostname, nil)
+	c.URL = newTestClient(t, "nohost").URL
+	c.Update(ctx, hostname, nil)
+	c.URL = newTestClient(t, `we"ird`).URL
+	c.Update(ctx, hostname, nil)
+	c.URL = newTestClient(t, "bogus").URL
+	c.Update(ctx, hostname, nil)
+	c.URL = "http://127.0.0.1:0/"
+	c.Update(ctx, hostname, nil)
+
+	var b strings.Builder
+	if err := c.WriteMetrics(&b); err != nil {
+		t.Fatal(err)
+	}
+	const want = `# HELP dyndns_requests_total Update requests attempted.
+# TYPE dyndns_requests_total counter
+dyndns_requests_total 6
+# HELP dyndns_request_errors_total Update requests that got no protocol response.
+# TYPE dyndns_request_errors_total counter
+dyndns_request_errors_total 1
+# HELP dyndns_updates_total Update results by return code.
+# TYPE dyndns_updates_total counter
+dyndns_updates_total{code="good"} 2
+dyndns_updates_total{code="invalid"} 2
+dyndns_updates_total{code="nohost"} 1
+`
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if st := c.Stats(); st.Codes[CodeGood] != 2 || st.Requests != 6 {
+		t.Errorf("Stats() = %+v", st)
+	}
+}
+
+func TestStatsSuccessCodes(t *testing.T) {
+	srv := newTestServer(t, "updated 1.2.3.4", nil, nil)
+	c, err := NewClient(srv.URL, AllowInsecure(), WithSuccessCodes("updated"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Update(context.Background(), hostname, nil)
+	if st := c.Stats(); st.Codes["updated"] != 1 || len(st.Codes) != 1 {
+		t.Errorf("Codes = %v, want the custom success code counted", st.Codes)
+	}
+}
@@ -0,0 +1,63 @@
+package dyndns
+
+import (
+	"context"
+	"net"
+)
+
+// SyncHostname makes hostname point to desired, updating it only if it
+// does not already. It first checks the address recorded by the Client's
+// last successful update, then the address hostname resolves to using the
+// Client's Resolver, and sends an update only if neither matches. A failed
+// lookup is treated as a mismatch. If desired is nil, it is found with
+// DetectIP.
+//
+// SyncHostname reports whether an update was sent and the address hostname
+// now points to.
+func (c *Client) SyncHostname(ctx context.Context, hostname string, desired net.IP) (updated bool, ip net.IP, err error) {
+	if desired == nil {
+		if desired, err = c.DetectIP(ctx); err != nil {
+			return false, nil, err
+		}
+	}
+	name, err := c.normalizeHostname(hostname)
+	if err != nil {
+		return false, nil, err
+	}
+	if _, last := c.lastUpdate(name); desired.Equal(last) {
+		return false, last, nil
+	}
+	if c.resolves(ctx, name, desired) {
+		c.mu.Lock()
+		c.host(name).ip = desired
+		c.mu.Unlock()
+		return false, desired, nil
+	}
+
+	res, err := c.Update(ctx, name, desired)
+	if err != nil {
+		return true, nil, err
+	}
+	if res.IP != nil {
+		return true, res.IP, nil
+	}
+	return true, desired, nil
+}
+
+// resolves reports whether hostname currently resolves to ip.
+func (c *Client) resolves(ctx context.Context, hostname string, ip net.IP) bool {
+	r := c.Resolver
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	addrs, err := r.LookupIP(ctx, "ip", hostname)
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if a.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package dyndns
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+// serveA answers every DNS query received on conn with an A record for
+// ip, or with no records for queries of other types.
+func serveA(conn net.PacketConn, ip net.IP) {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		q := buf[:n]
+		end := 12 // end of the question section
+		for q[end] != 0 {
+			end += int(q[end]) + 1
+		}
+		end += 5 // root label, type, class
+		qtype := binary.BigEndian.Uint16(q[end-4:])
+
+		msg := append([]byte(nil), q[:end]...)
+		msg[2], msg[3] = 0x84, 0x00 // response, authoritative
+		if qtype == 1 {
+			binary.BigEndian.PutUint16(msg[6:], 1)
+			msg = append(msg, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4)
+			msg = append(msg, ip.To4()...)
+		}
+		conn.WriteTo(msg, addr)
+	}
+}
+
+func newSyncClient(t *testing.T, published net.IP, n *int) *Client {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go serveA(conn, published)
+
+	srv := newTestServer(t, "good 5.6.7.8", n, nil)
+	return &Client{URL: srv.URL, Resolver: NewDNSResolver(conn.LocalAddr().String())}
+}
+
+func TestSyncHostnameMatch(t *testing.T) {
+	var n int
+	c := newSyncClient(t, net.IPv4(1, 2, 3, 4), &n)
+	updated, ip, err := c.SyncHostname(context.Background(), hostname, net.IPv4(1, 2, 3, 4))
+	if err != nil || updated || !ip.Equal(net.IPv4(1, 2, 3, 4)) {
+		t.Errorf("got %v, %v, %v; want no update to 1.2.3.4", updated, ip, err)
+	}
+	if n != 0 {
+		t.Errorf("%d update requests, want 0", n)
+	}
+}
+
+func TestSyncHostnameMismatch(t *testing.T) {
+	var n int
+	c := newSyncClient(t, net.IPv4(1, 2, 3, 4), &n)
+	ctx := context.Background()
+	updated, ip, err := c.SyncHostname(ctx, hostname, net.IPv4(5, 6, 7, 8))
+	if err != nil || !updated || !ip.Equal(net.IPv4(5, 6, 7, 8)) {
+		t.Errorf("got %v, %v, %v; want update to 5.6.7.8", updated, ip, err)
+	}
+
+	// The DNS answer is stale now, but the Client remembers the update.
+	updated, _, err = c.SyncHostname(ctx, hostname, net.IPv4(5, 6, 7, 8))
+	if err != nil || updated {
+		t.Errorf("second sync: updated %v, err %v", updated, err)
+	}
+	if n != 1 {
+		t.Errorf("%d update requests, want 1", n)
+	}
+}
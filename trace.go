@@ -0,0 +1,86 @@
+package dyndns
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings break down the time spent on an update request. Phases that
+// did not happen, such as DNS and TLS on a reused connection, are zero.
+type Timings struct {
+	DNS       time.Duration // resolving the service's hostname
+	Connect   time.Duration // establishing the TCP connection
+	TLS       time.Duration // TLS handshake
+	FirstByte time.Duration // from request written to first response byte
+	Total     time.Duration // from request start to body read
+}
+
+// tracer collects Timings from httptrace callbacks, which may be called
+// from several goroutines.
+type tracer struct {
+	mu                              sync.Mutex
+	start, dns, connect, tls, wrote time.Time
+	t                               Timings
+}
+
+func (tr *tracer) since(start time.Time) time.Duration {
+	if start.IsZero() {
+		return 0
+	}
+	return time.Since(start)
+}
+
+func (tr *tracer) set(f func()) {
+	tr.mu.Lock()
+	f()
+	tr.mu.Unlock()
+}
+
+// withTrace returns a context that records request timings, or ctx and
+// nil if the Client was not created with WithTrace. Call done with the
+// tracer when the request is finished to get the Timings.
+func (c *Client) withTrace(ctx context.Context) (context.Context, *tracer) {
+	if !c.trace {
+		return ctx, nil
+	}
+	tr := &tracer{start: time.Now()}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { tr.set(func() { tr.dns = time.Now() }) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { tr.set(func() { tr.t.DNS = tr.since(tr.dns) }) },
+		ConnectStart: func(string, string) {
+			tr.set(func() {
+				if tr.connect.IsZero() {
+					tr.connect = time.Now()
+				}
+			})
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				tr.set(func() { tr.t.Connect = tr.since(tr.connect) })
+			}
+		},
+		TLSHandshakeStart: func() { tr.set(func() { tr.tls = time.Now() }) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			tr.set(func() { tr.t.TLS = tr.since(tr.tls) })
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) { tr.set(func() { tr.wrote = time.Now() }) },
+		GotFirstResponseByte: func() {
+			tr.set(func() { tr.t.FirstByte = tr.since(tr.wrote) })
+		},
+	}), tr
+}
+
+// done returns the recorded Timings, or nil if tr is nil.
+func (tr *tracer) done() *Timings {
+	if tr == nil {
+		return nil
+	}
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	t := tr.t
+	t.Total = time.Since(tr.start)
+	return &t
+}
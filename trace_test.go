@@ -0,0 +1,44 @@
+package dyndns
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithTrace(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		io.WriteString(w, "good 1.2.3.4")
+	}))
+	defer srv.Close()
+	c, err := NewClient(srv.URL, AllowInsecure(), WithTrace())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.HTTPClient = srv.Client()
+	res, err := c.Update(context.Background(), hostname, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := res.Timings
+	if tm == nil {
+		t.Fatal("no timings recorded")
+	}
+	if tm.Connect <= 0 || tm.TLS <= 0 || tm.FirstByte < 20*time.Millisecond || tm.Total < tm.FirstByte {
+		t.Errorf("timings = %+v", tm)
+	}
+}
+
+func TestTraceOffByDefault(t *testing.T) {
+	res, err := newTestClient(t, "good 1.2.3.4").Update(context.Background(), hostname, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Timings != nil {
+		t.Errorf("timings recorded without WithTrace: %+v", res.Timings)
+	}
+}
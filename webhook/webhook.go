@@ -0,0 +1,126 @@
+// Package webhook reports address changes of dynamic DNS hostnames to an
+// HTTP endpoint.
+//
+// A Webhook's Notify method matches the dyndns Client's OnChange callback:
+//
+//	c.OnChange = webhook.Notifier("https://example.com/hooks/dns")
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// An Event is the JSON body posted for an address change. Addresses are
+// encoded as strings; Old is empty if the previous address is unknown.
+type Event struct {
+	Hostname string    `json:"hostname"`
+	Old      net.IP    `json:"old"`
+	New      net.IP    `json:"new"`
+	Time     time.Time `json:"time"`
+}
+
+// DefaultTimeout is the default for Webhook.Timeout.
+const DefaultTimeout = 10 * time.Second
+
+// A Webhook posts an Event to URL for each address change.
+type Webhook struct {
+	URL string
+
+	// HTTPClient is used to send events. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// Timeout limits the time spent posting an event.
+	// If zero, DefaultTimeout is used.
+	Timeout time.Duration
+
+	// OnError, if non-nil, is called when an event could not be delivered.
+	OnError func(error)
+
+	// Secret, if non-empty, is shared with the receiver to sign each
+	// event: its body is sent with a Sign(Secret, body) signature, in the
+	// SignatureHeader header.
+	Secret []byte
+
+	// SignatureHeader names the signature header.
+	// If empty, DefaultSignatureHeader is used.
+	SignatureHeader string
+}
+
+// DefaultSignatureHeader is the default for Webhook.SignatureHeader.
+const DefaultSignatureHeader = "X-Dyndns-Signature"
+
+// Sign returns the signature of body with secret: "sha256=" followed by
+// the hex-encoded HMAC-SHA256, as in GitHub's webhooks.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of body with secret,
+// for receivers. It takes constant time.
+func Verify(secret, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Notifier returns a callback that posts each address change to url.
+// Delivery errors are dropped; use a Webhook to handle them.
+func Notifier(url string) func(hostname string, old, new net.IP) {
+	return (&Webhook{URL: url}).Notify
+}
+
+// Notify posts an Event for hostname changing from old to new. It blocks
+// until the endpoint replies or the Timeout expires.
+func (w *Webhook) Notify(hostname string, old, new net.IP) {
+	if err := w.Send(context.Background(), Event{hostname, old, new, time.Now().UTC()}); err != nil && w.OnError != nil {
+		w.OnError(err)
+	}
+}
+
+// Send posts ev. Statuses other than 2xx are errors.
+func (w *Webhook) Send(ctx context.Context, ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	timeout := w.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(w.Secret) > 0 {
+		header := w.SignatureHeader
+		if header == "" {
+			header = DefaultSignatureHeader
+		}
+		req.Header.Set(header, Sign(w.Secret, body))
+	}
+	client := w.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook: unexpected status %s", resp.Status)
+	}
+	return nil
+}
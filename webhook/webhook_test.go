@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotifier(t *testing.T) {
+	var body []byte
+	var contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("method = %s", r.Method)
+		}
+		contentType = r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	Notifier(srv.URL)("test.dyndns.org", net.IPv4(1, 2, 3, 4), net.ParseIP("2001:db8::1"))
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("%s: %v", body, err)
+	}
+	for key, want := range map[string]any{
+		"hostname": "test.dyndns.org",
+		"old":      "1.2.3.4",
+		"new":      "2001:db8::1",
+	} {
+		if payload[key] != want {
+			t.Errorf("%s = %v, want %v", key, payload[key], want)
+		}
+	}
+	ts, _ := payload["time"].(string)
+	if when, err := time.Parse(time.RFC3339, ts); err != nil || time.Since(when) > time.Minute {
+		t.Errorf("time = %q", ts)
+	}
+	if len(payload) != 4 {
+		t.Errorf("payload has %d fields: %s", len(payload), body)
+	}
+
+	Notifier(srv.URL)("test.dyndns.org", nil, net.IPv4(1, 2, 3, 4))
+	if !strings.Contains(string(body), `"old":""`) {
+		t.Errorf("first change: %s", body)
+	}
+}
+
+func TestWebhookError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	var got error
+	w := &Webhook{URL: srv.URL, OnError: func(err error) { got = err }}
+	w.Notify("test.dyndns.org", nil, net.IPv4(1, 2, 3, 4))
+	if got == nil {
+		t.Error("no error for 500 response")
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Known value, as computed by: printf 'hello' | openssl dgst -sha256 -hmac secret
+	const want = "sha256=88aab3ede8d3adf94d26ab90d3bafd4a2083070c3bcce9c014ee04a443847c0b"
+	if got := Sign([]byte("secret"), []byte("hello")); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if !Verify([]byte("secret"), []byte("hello"), want) || Verify([]byte("other"), []byte("hello"), want) {
+		t.Error("Verify does not match Sign")
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	secret := []byte("s3cret")
+	var body []byte
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+	ev := Event{"test.dyndns.org", nil, net.IPv4(1, 2, 3, 4), time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	w := &Webhook{URL: srv.URL, Secret: secret}
+	if err := w.Send(context.Background(), ev); err != nil {
+		t.Fatal(err)
+	}
+	const payload = `{"hostname":"test.dyndns.org","old":"","new":"1.2.3.4","time":"2024-01-02T03:04:05Z"}`
+	if string(body) != payload {
+		t.Errorf("body = %s, want %s", body, payload)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	if got, want := header.Get(DefaultSignatureHeader), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+
+	w.SignatureHeader = "X-Hub-Signature-256"
+	w.Send(context.Background(), ev)
+	if !Verify(secret, body, header.Get("X-Hub-Signature-256")) || header.Get(DefaultSignatureHeader) != "" {
+		t.Errorf("custom header: %v", header)
+	}
+
+	w = &Webhook{URL: srv.URL}
+	w.Send(context.Background(), ev)
+	if header.Get(DefaultSignatureHeader) != "" {
+		t.Error("unsigned webhook sent a signature")
+	}
+}